@@ -1,22 +1,22 @@
 package common
 
 import (
+	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
-	"strings"
+	"reflect"
 
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
 	"golang.org/x/tools/go/ast/inspector"
 )
 
-const mongoPkgName = "go.mongodb.org/mongo-driver/mongo"
-
-const fullClientPkg = mongoPkgName + ".Client"
-const fullDbPkg = mongoPkgName + ".Database"
-
-const optsPkgName = "go.mongodb.org/mongo-driver/mongo/options"
+// Packages of the driver, relative to the module path of its major version
+const mongoPkgName = "mongo"
+const optsPkgName = "mongo/options"
+const bsonPkgName = "bson"
+const primitivePkgName = "bson/primitive"
 
 var StableAnalyzer = &analysis.Analyzer{
 	Name: "gostable",
@@ -25,87 +25,41 @@ var StableAnalyzer = &analysis.Analyzer{
 	Requires: []*analysis.Analyzer{
 		inspect.Analyzer,
 	},
+	FactTypes:  []analysis.Fact{new(resultFact)},
+	ResultType: reflect.TypeOf((*Result)(nil)),
 }
 
-var unstableFunctions = map[string]map[string][]string{
-	mongoPkgName: {
-		"Client":     {"Watch"},
-		"Collection": {"Distinct", "SearchIndexes", "Watch"},
-		"Database":   {"Watch"},
-	},
-	optsPkgName: {
-		"CreateCollectionOptions": {"SetCapped", "SetDefaultIndexOptions", "SetMaxDocuments", "SetSizeInBytes", "SetStorageEngine"},
-		"FindOneAndDeleteOptions": {"SetMax", "SetMaxAwaitTime", "SetMin", "SetNoCursorTimeout", "SetOplogReplay", "SetReturnKey",
-			"SetShowRecordID"},
-		"FindOneAndReplaceOptions": {"SetMax", "SetMaxAwaitTime", "SetMin", "SetNoCursorTimeout", "SetOplogReplay", "SetReturnKey",
-			"SetShowRecordID"},
-		"FindOneAndUpdateOptions": {"SetMax", "SetMaxAwaitTime", "SetMin", "SetNoCursorTimeout", "SetOplogReplay", "SetReturnKey",
-			"SetShowRecordID"},
-		"FindOneOptions": {"SetMax", "SetMaxAwaitTime", "SetMin", "SetNoCursorTimeout", "SetOplogReplay", "SetReturnKey",
-			"SetShowRecordID"},
-		"FindOptions": {"SetCursorType", "SetMax", "SetMaxAwaitTime", "SetMin", "SetNoCursorTimeout", "SetOplogReplay", "SetReturnKey",
-			"SetShowRecordID"},
-		"IndexOptions": {"SetBackground", "SetBucketSize", "SetSparse", "SetStorageEngine"},
-	},
-}
-
-var unstableOptionsStructs = map[string][]string{
-	"CreateCollectionOptions": {"Capped", "DefaultIndexOptions", "MaxDocuments", "SizeInBytes", "StorageEngine"},
-	"CursorType":              {"Tailable", "TailableAwait"},
-	"FindOneAndDeleteOptions": {"Max", "MaxAwaitTime", "Min", "NoCursorTimeout", "OplogReplay", "ReturnKey",
-		"ShowRecordID"},
-	"FindOneAndReplaceOptions": {"Max", "MaxAwaitTime", "Min", "NoCursorTimeout", "OplogReplay", "ReturnKey",
-		"ShowRecordID"},
-	"FindOneAndUpdateOptions": {"Max", "MaxAwaitTime", "Min", "NoCursorTimeout", "OplogReplay", "ReturnKey",
-		"ShowRecordID"},
-	"FindOneOptions": {"Max", "MaxAwaitTime", "Min", "NoCursorTimeout", "OplogReplay", "ReturnKey",
-		"ShowRecordID"},
-	"FindOptions": {"CursorType", "Max", "MaxAwaitTime", "Min", "NoCursorTimeout", "OplogReplay", "ReturnKey",
-		"ShowRecordID"},
-	"IndexOptions": {"Background", "BucketSize", "Sparse", "StorageEngine"},
-}
-
-var restrictedStages = []string{"$currentOp", "$indexStats", "$listLocalSessions", "$listSessions", "$planCacheStats", "$search"}
-
-// commands that are supported without limitations or caveats
-var stableCommands = []string{"count", "abortTransaction", "authenticate", "bulkWrite", "collMod", "commitTransaction",
-	"delete", "drop", "dropDatabase", "dropIndexes", "endSessions", "findAndModify", "getMore", "insert", "hello",
-	"killCursors", "listCollections", "listDatabases", "listIndexes", "ping", "refreshSessions", "update",
+// Result is the result of the analyzer on a package, which the reports of gostable read.
+type Result struct {
+	// Findings are the reported diagnostics, with their details.
+	Findings []Finding
+	// Calls are the calls of the inventory, with -inventory-calls.
+	Calls []InventoryCall
 }
 
-//var semistableCommands = []string{"aggregate", "create", "createIndexes", "explain", "find"}
-
-/*
-// The Stable API command list will change a lot less than the complete list of commands.
-// I stopped adding at the sharding commands.
-// https://www.mongodb.com/docs/manual/reference/command/#sharding-commands
+func run(pass *analysis.Pass) (interface{}, error) {
+	cat, err := activeCatalog()
+	if err != nil {
+		return nil, err
+	}
+	// The driver's own packages are analyzed as dependencies only
+	if cat.driverModule(pass.Pkg.Path()) != "" {
+		return &Result{}, nil
+	}
 
-	var unstableCommands = []string{
-		// Aggregation Commands
-		"distinct", "mapReduce",
-		// Geospatial Commands
-		"geoSearch",
-		// Query and Write Operation Commands
-		"resetError",
-		// Query Plan Cache Commands
-		"planCacheClear", "planCacheClearFilters", "planCacheListFilters", "planCacheSetFilter",
-		// Authentication Commands
-		"logout",
-		// User Management Commands
-		"createUser", "dropAllUsersFromDatabase", "dropUser", "grantRolesToUser", "revokeRolesFromUser", "updateUser", "usersInfo",
-		// Role Management Commands
-		"createRole", "dropRole", "dropAllRolesFromDatabase", "grantPrivilegesToRole", "grantRolesToRole", "invalidateUserCache",
-		"revokePrivilegesFromRole", "revokeRolesFromRole", "rolesInfo", "updateRole",
-		// Replication Commands
-		"applyOps", "replSetAbortPrimaryCatchUp", "replSetFreeze", "replSetGetConfig", "replSetGetStatus", "replSetInitiate",
-		"replSetMaintenance", "replSetReconfig", "replSetResizeOplog", "replSetStepDown", "replSetSyncFrom",
+	filter, err := activeFilter()
+	if err != nil {
+		return nil, err
 	}
-*/
-func run(pass *analysis.Pass) (interface{}, error) {
+
+	c := newChecker(pass, cat, filter)
 	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	if inventoryFlag {
+		c.exportResultFacts()
+		return &Result{Calls: c.takeInventory(inspect)}, nil
+	}
 
 	nodeFilter := []ast.Node{
-		(*ast.BasicLit)(nil),
 		(*ast.CallExpr)(nil),
 		(*ast.CompositeLit)(nil),
 		(*ast.SelectorExpr)(nil),
@@ -115,379 +69,275 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		if push {
 			return true
 		}
-		switch x := node.(type) {
-
-		// Look for any of the restricted aggregation stages as a string.
-		// The strings are sufficiently specific that this is not likely to have false positives,
-		// i.e. same string but not the MongoDB agg stage.
-		case *ast.BasicLit:
-			if x.Kind == token.STRING {
-				//fmt.Printf("string value: %v\n", x.Value)
-				for _, target := range restrictedStages {
-					if strings.Contains(x.Value, target) {
-						pass.Reportf(x.Pos(), "Aggregation stage '%s' is not supported by the MongoDB Stable API", target)
-					}
-				}
-			}
+		switch node.(type) {
 
 		// Look at all function calls
 		case *ast.CallExpr:
 			call := node.(*ast.CallExpr)
-			callPkgName, callFnName := pkgPathDotTypeAndFunction(pass, call)
-			// Make a general warning about direct use of RunCommand. We might not catch all possible unsupported command constructions.
-			if (callPkgName == fullClientPkg || callPkgName == fullDbPkg) && callFnName == "RunCommand" {
-				pass.Reportf(call.Pos(), "Any use of RunCommand should be reviewed against the MongoDB Stable API command list")
-				// and also try to find the actual command passed to RunCommand
-				analyzeRunCommand(pass, call, stack)
-			} else {
-				// Check against unstableFunctions map
-				for pkg, driverFnMap := range unstableFunctions {
-					for driverType, fnNames := range driverFnMap {
-						for _, fnName := range fnNames {
-							fullPkg := pkg + "." + driverType
-							if isPkgDotFunction(pass, call, fullPkg, fnName) {
-								pass.Reportf(call.Pos(), "Function %v.%v is not supported by the MongoDB Stable API", driverType, fnName)
-							}
+			for _, method := range c.calledMethods(call) {
+				// Check the command passed to RunCommand or RunCommandCursor, or ask for a review when it cannot be found
+				if c.symbols().commands[method.fn] {
+					c.analyzeRunCommand(call, method.fn, method.args)
+					continue
+				}
+
+				// Check against the catalog's unstable methods
+				if rule, ok := c.symbols().rule(method.fn); ok {
+					clients := c.receiverClients(c.callReceiver(call))
+					if isOptionsMethod(cat, method.fn.Type().(*types.Signature)) {
+						clients = c.optionClients(call)
+					}
+					d := c.diagnostic(ruleUnstableMethod, symbolDetails(rule, CategoryMethod, method.fn.Name()), clients, call.Pos(), "Function %v.%v is not supported by the MongoDB Stable API%s%s", rule.Type, method.fn.Name(),
+						cat.ruleNote(rule), viaNote(method.via))
+					if method.via == "" {
+						if fix, ok := c.methodFix(call, method.fn, stack); ok {
+							d.SuggestedFixes = []analysis.SuggestedFix{fix}
 						}
 					}
+					c.record(d)
 				}
 			}
 
 		// Look for any unsupported struct fields.
 		// We will assume that simply referring to them or setting them is unsupported.
 		case *ast.CompositeLit:
-			compLit, ok := node.(*ast.CompositeLit)
-			if !ok {
-				return false
-			}
-
-			packageName, structName, ok := getStructInfo(pass, compLit.Type)
-			if !ok {
-				return false
-			}
-
-			if packageName != optsPkgName {
-				return false
-			}
-
-			members, ok := unstableOptionsStructs[structName]
-			if !ok {
+			compLit := node.(*ast.CompositeLit)
+			if _, ok := pass.TypesInfo.TypeOf(compLit).Underlying().(*types.Struct); !ok {
 				return false
 			}
 
 			for _, elt := range compLit.Elts {
 				if kv, ok := elt.(*ast.KeyValueExpr); ok {
 					if ident, ok := kv.Key.(*ast.Ident); ok {
-						for _, member := range members {
-							if ident.Name == member {
-								pass.Reportf(ident.Pos(), "Struct field %s.%s is not supported by the MongoDB Stable API", structName, member)
-								break
+						if rule, ok := c.symbols().rule(pass.TypesInfo.Uses[ident]); ok {
+							clients := c.optionClients(compLit)
+							d := c.diagnostic(ruleUnstableField, symbolDetails(rule, CategoryStructField, ident.Name), clients, ident.Pos(), "Struct field %s.%s is not supported by the MongoDB Stable API%s", rule.Type, ident.Name,
+								cat.ruleNote(rule))
+							if fix, ok := c.fieldFix(compLit, kv, ident.Name); ok {
+								d.SuggestedFixes = []analysis.SuggestedFix{fix}
 							}
+							c.record(d)
 						}
 					}
 				}
 			}
 
-		// Look for unsupported cursor types
+		// Look for unsupported struct fields that are set or read, and unsupported cursor types
 		case *ast.SelectorExpr:
 			selExpr := node.(*ast.SelectorExpr)
+			obj := pass.TypesInfo.Uses[selExpr.Sel]
+			rule, ok := c.symbols().rule(obj)
+			if !ok {
+				return false
+			}
 
-			switch selExpr.Sel.Name {
-			case "Tailable", "TailableAwait":
-				xIdent, ok := selExpr.X.(*ast.Ident)
-				if !ok {
-					return false
-				}
-
-				typ := pass.TypesInfo.TypeOf(xIdent)
-				if typ == nil {
-					return false
+			switch obj.(type) {
+			case *types.Var:
+				access := "read"
+				if isAssigned(selExpr, stack) {
+					access = "set"
 				}
-
-				if named, ok := typ.(*types.Named); ok {
-					obj := named.Obj()
-					pkg := obj.Pkg()
-					if pkg == nil || pkg.Path() != optsPkgName {
-						return false
+				c.report(ruleUnstableField, symbolDetails(rule, CategoryStructField, selExpr.Sel.Name), c.optionClients(selExpr), selExpr.Sel.Pos(), "Struct field %s.%s is %s, which is not supported by the MongoDB Stable API%s", rule.Type,
+					selExpr.Sel.Name, access, cat.ruleNote(rule))
+			case *types.Const:
+				// The constants are referred to as options.Tailable, mostly passed to a setter
+				var usage ast.Expr = selExpr
+				for i := len(stack) - 2; i >= 0; i-- {
+					if call, ok := stack[i].(*ast.CallExpr); ok {
+						usage = call
+						break
 					}
 				}
-
-				pass.Reportf(node.Pos(), "Struct field CursorType.%s is not supported by the MongoDB Stable API", selExpr.Sel.Name)
+				c.report(ruleUnstableField, symbolDetails(rule, CategoryCursorType, selExpr.Sel.Name), c.optionClients(usage), node.Pos(), "Struct field %s.%s is not supported by the MongoDB Stable API%s", rule.Type, selExpr.Sel.Name,
+					cat.ruleNote(rule))
 			}
 		}
 		return false
 	})
 
-	return nil, nil
+	c.checkPipelines(inspect)
+	c.checkClients(inspect)
+	c.exportResultFacts()
+	c.checkResultFacts(inspect)
+	return &Result{Findings: c.flush()}, nil
 }
 
-func getStructInfo(pass *analysis.Pass, expr ast.Expr) (string, string, bool) {
-	typ := pass.TypesInfo.TypeOf(expr)
-	if typ == nil {
-		return "", "", false
-	}
-
-	if named, ok := typ.(*types.Named); ok {
-		obj := named.Obj()
-		if pkg := obj.Pkg(); pkg != nil {
-			return pkg.Path(), obj.Name(), true
-		}
+// viaNote returns the note on how a method is called, when it is not named by the call.
+func viaNote(via string) string {
+	if via == "" {
+		return ""
 	}
-
-	return "", "", false
+	return ", called through " + via
 }
 
-func pkgPathDotTypeAndFunction(pass *analysis.Pass, call *ast.CallExpr) (string, string) {
-	// Check if the call expression is a selector expression
-	selExpr, ok := call.Fun.(*ast.SelectorExpr)
-	if !ok {
-		return "", ""
-	}
-
-	// Check if the selector's X expression is a package identifier
-	_, ok = selExpr.X.(*ast.Ident)
-	if !ok {
-		return "", selExpr.Sel.Name
-	}
-
-	typ := pass.TypesInfo.TypeOf(selExpr.X)
-	if typ == nil {
-		return "", selExpr.Sel.Name
-	}
-
-	typStr := typ.String()
-	if len(typStr) > 0 && typStr[0] == '*' {
-		typStr = typStr[1:]
-	}
-
-	return typStr, selExpr.Sel.Name
-}
-
-func analyzeRunCommand(pass *analysis.Pass, call *ast.CallExpr, stack []ast.Node) {
-	//fmt.Println("In analyzeRunCommand")
-	// Get the command argument (second argument)
-	if len(call.Args) < 2 {
-		return
-	}
-	cmdArg := call.Args[1]
-
-	// Check if the command argument is a bson.D literal
-	if bsonDLit, ok := cmdArg.(*ast.CompositeLit); ok {
-		//fmt.Println("CompositeLit")
-		if isBsonDType(pass.TypesInfo.TypeOf(bsonDLit)) {
-			// Analyze the bson.D literal
-			//fmt.Println("analyzeCommandLiteral 1")
-			analyzeCommandLiteral(pass, bsonDLit)
-			return
-		}
-	} else {
-		// Check if the command argument is a variable
-		if ident, ok := cmdArg.(*ast.Ident); ok {
-			//fmt.Println("Ident")
-			if isBsonDType(pass.TypesInfo.ObjectOf(ident).Type()) {
-				//fmt.Println("isBsonDType")
-				// Find the variable declaration and analyze its value
-				if assignStmt := findVariableAssignment(pass, ident, stack); assignStmt != nil {
-					//fmt.Println("assignStmt != nil")
-					if bsonDLit, ok := assignStmt.Rhs[0].(*ast.CompositeLit); ok {
-						//fmt.Println("analyzeCommandLiteral 2")
-						//fmt.Printf("bsonDLit %v\n", bsonDLit.Type)
-
-						analyzeCommandLiteral(pass, bsonDLit)
-					}
+// isAssigned reports whether the selector, the last node of the stack, is assigned to or incremented.
+func isAssigned(selExpr *ast.SelectorExpr, stack []ast.Node) bool {
+	var expr ast.Expr = selExpr
+	for i := len(stack) - 2; i >= 0; i-- {
+		switch parent := stack[i].(type) {
+		case *ast.ParenExpr:
+			expr = parent
+			continue
+		case *ast.AssignStmt:
+			for _, lhs := range parent.Lhs {
+				if lhs == expr {
+					return true
 				}
 			}
+		case *ast.IncDecStmt:
+			return parent.X == expr
 		}
+		return false
 	}
+	return false
 }
 
-func isBsonDType(typ types.Type) bool {
-	//fmt.Printf("typ: %v\n", typ.String())
-	return typ.String() == "bson.D" || typ.String() == "go.mongodb.org/mongo-driver/bson/primitive.D"
-}
-
-func findVariableAssignment(pass *analysis.Pass, ident *ast.Ident, stack []ast.Node) *ast.AssignStmt {
-	var assignStmt *ast.AssignStmt
-
-	// debugging, print out stack
-	/*
-		fmt.Println("Stack")
-		for _, stackNode := range stack {
-			fmt.Printf("%T\n", stackNode)
-			//ast.Print(nil, stackNode)
-		}
-		fmt.Println("End Stack")
-
-		for i := len(stack) - 1; i >= 0; i-- {
-			fmt.Printf("%T\n", stack[i])
+// analyzeRunCommand checks the command passed to RunCommand or RunCommandCursor, which is the first key
+// of the document passed as the 2nd argument: a bson.D, a map such as bson.M, or either encoded with
+// bson.Marshal. The outcome is one of three: a supported command is not reported, an unsupported
+// command is, and when the command cannot be determined the call is reported for review. The options,
+// e.g. options.RunCmd().SetReadPreference(...), do not change the command.
+func (c *checker) analyzeRunCommand(call *ast.CallExpr, fn *types.Func, args []ast.Expr) {
+	fnName := fn.Name()
+	clients := c.receiverClients(c.callReceiver(call))
+	unresolved := true
+	if len(args) >= 2 {
+		candidates := c.docs.resolveMarshaled(args[1], map[ast.Node]bool{})
+		if len(candidates) == 0 {
+			// e.g. a parameter, which may still be of a struct type
+			candidates = []ast.Expr{args[1]}
 		}
-		fmt.Println("End Reverse")
-	*/
-
-	// Search for the assignment, going up the call stack until found
-	for i := len(stack) - 1; i >= 0; i-- {
-		//fmt.Printf("%T\n", stack[i])
-
-		ast.Inspect(stack[i], func(node ast.Node) bool {
-			switch stmt := node.(type) {
-			case *ast.AssignStmt:
-				for _, lhs := range stmt.Lhs {
-					if lhsIdent, ok := lhs.(*ast.Ident); ok && lhsIdent.Name == ident.Name {
-						assignStmt = stmt
-						return false
-					}
-				}
-			}
-			return true
-		})
-
-		if assignStmt != nil {
-			break
-		}
-	}
-
-	// The previous loop ended with the ast.File containing the runCommand call.
-	// If an assignment of the command variable was not found in that file, the following
-	// looks for that declaration in all Files. Note that this might find some false positives.
-	if assignStmt == nil {
-		for _, file := range pass.Files {
-			ast.Inspect(file, func(node ast.Node) bool {
-				switch stmt := node.(type) {
-				case *ast.AssignStmt:
-					for _, lhs := range stmt.Lhs {
-						if lhsIdent, ok := lhs.(*ast.Ident); ok && lhsIdent.Name == ident.Name {
-							assignStmt = stmt
-							return false
+		unresolved = false
+		for _, x := range candidates {
+			var names []string
+			switch x := x.(type) {
+			case *ast.CompositeLit:
+				// a command document written here
+				if key, ok := c.commandKey(x); ok {
+					names = key.names
+					related := c.commandRelated(call, fnName, x)
+					for _, name := range names {
+						var fixes []analysis.SuggestedFix
+						if name == "count" {
+							if fix, ok := c.countFix(call, fn, x); ok {
+								fixes = []analysis.SuggestedFix{fix}
+							}
 						}
+						c.checkCommand(clients, related, fixes, key.elt.Pos(), name, "")
+						c.checkCommandFields(clients, related, x, name)
 					}
+				} else if len(x.Elts) > 1 && isMapType(c.docs.typeOf(x)) {
+					// a map with several keys, any of which may be encoded first
+					for _, elt := range x.Elts {
+						names = append(names, c.docs.elementKey(elt).names...)
+					}
+					d := c.diagnostic(ruleUnorderedCommand, runDetails(ruleUnorderedCommand, fnName), clients, x.Pos(), "The keys of the command document passed to %s have no defined order, "+
+						"so its command is not determined; use a bson.D", fnName)
+					d.Related = append(c.commandRelated(call, fnName, x)[1:], d.Related...)
+					c.record(d)
 				}
-				return true
-			})
-
-			if assignStmt != nil {
-				break
-			}
-		}
-	}
-
-	return assignStmt
-}
-
-func analyzeCommandLiteral(pass *analysis.Pass, x *ast.CompositeLit) {
-	//fmt.Println("Inside analyzeCommandLiteral")
-
-	if x.Type != nil {
-		switch t := x.Type.(type) {
-		case *ast.SelectorExpr:
-			//fmt.Printf("Sel.Name: %v\n", t.Sel.Name)
-			if t.Sel.Name == "D" {
-				if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "bson" {
-					// Access the bson.D type
-					bsonDType := pass.TypesInfo.TypeOf(x)
-					//fmt.Println("ACL 6")
-					// Check if the bsonDType is *types.Named
-					if named, ok := bsonDType.(*types.Named); ok {
-						//fmt.Println("ACL 5")
-						if named.Obj().Name() == "D" && named.Obj().Pkg().Name() == "primitive" {
-							//fmt.Println("ACL 4")
-							// Get the first element of the bson.D slice
-							if len(x.Elts) > 0 {
-								//fmt.Println("ACL 2")
-								if compositeElt, ok := x.Elts[0].(*ast.CompositeLit); ok {
-									//fmt.Println("ACL 1")
-									// Extract the command name from the composite literal
-									commandName := getCommandName(compositeElt)
-									//fmt.Printf("commandName: %v\n", commandName)
-									if commandName != "" {
-										if isStableCommand(commandName) {
-											//fmt.Printf("Stable: %v\n", commandName)
-										} else {
-											pass.Reportf(compositeElt.Pos(), "Command %s is not supported by the MongoDB Stable API", commandName)
-										}
-									}
-								}
-							}
-						}
+			case *ast.CallExpr:
+				// a command document returned by a function
+				if producer := c.resultProducer(x); producer != nil {
+					for _, cmd := range c.resultOf(producer).Commands {
+						names = append(names, cmd.Name)
+						c.checkCommand(clients, nil, nil, args[1].Pos(), cmd.Name, fmt.Sprintf(", in the result of %s (%s)", funcName(producer), cmd.Origin))
 					}
 				}
 			}
-		}
-	}
-}
-
-func getCommandName(compositeElt *ast.CompositeLit) string {
-	//ast.Print(nil, compositeElt)
-	if len(compositeElt.Elts) > 0 {
-		//fmt.Printf("type: %T\n", compositeElt.Elts[0])
-		if kv, ok := compositeElt.Elts[0].(*ast.KeyValueExpr); ok {
-			if ident, ok := kv.Key.(*ast.Ident); ok {
-				if ident.Name == "Key" {
-					if val, ok := kv.Value.(*ast.BasicLit); ok {
-						if val.Kind == token.STRING {
-							return stripQuotes(val.Value)
-						}
+			if len(names) == 0 {
+				// a value of a struct type, whose first fields are encoded whatever the value
+				if keys := c.docs.typeKeys(c.docs.typeOf(x), x); len(keys) > 0 {
+					names = keys[0].names
+					for _, name := range names {
+						c.checkCommand(clients, c.commandRelated(call, fnName, x), nil, x.Pos(), name, "")
 					}
 				}
 			}
+			if len(names) == 0 {
+				unresolved = true
+			}
 		}
 	}
-	return ""
-}
 
-func stripQuotes(s string) string {
-	if len(s) > 0 && s[0] == '"' {
-		s = s[1:]
-	}
-	if len(s) > 0 && s[len(s)-1] == '"' {
-		s = s[:len(s)-1]
+	if unresolved {
+		c.report(ruleUnresolvedCommand, runDetails(ruleUnresolvedCommand, fnName), clients, call.Pos(), "The command passed to %s could not be determined, review it against the MongoDB Stable API command list", fnName)
 	}
-	return s
 }
 
-func isStableCommand(cmd string) bool {
-	// Check if the command is part of the MongoDB Stable API
-	// You can customize this based on your specific requirements
-
-	for _, stableCmd := range stableCommands {
-		if cmd == stableCmd {
-			return true
-		}
+// commandKey returns the key of a command document literal that names its command: the first key of
+// a bson.D, keyed or not, the first encoded field of a struct, or the only key of a map such as bson.M.
+func (c *checker) commandKey(doc *ast.CompositeLit) (docKey, bool) {
+	keys := c.docs.literalKeys(doc)
+	if len(keys) == 0 || len(keys) > 1 && isMapType(c.docs.typeOf(doc)) {
+		return docKey{}, false
 	}
-
-	return false
+	return keys[0], true
 }
 
-func isPkgDotFunction(pass *analysis.Pass, call *ast.CallExpr, packagePath, functionName string) bool {
-	// Check if the call expression is a selector expression
-	selExpr, ok := call.Fun.(*ast.SelectorExpr)
+// checkCommand reports name if it is not a supported command run by clients, with the related
+// information of its command document and the fixes of the command, if any.
+func (c *checker) checkCommand(clients usageClients, related []analysis.RelatedInformation, fixes []analysis.SuggestedFix, pos token.Pos, name, note string) {
+	message, ok := c.commandMessage(name)
 	if !ok {
-		return false
+		return
 	}
+	d := c.diagnostic(ruleUnstableCommand, c.cat.commandDetails(name), clients, pos, "%s%s", message, note)
+	d.Related = append(append([]analysis.RelatedInformation(nil), related...), d.Related...)
+	d.SuggestedFixes = fixes
+	c.record(d)
+}
 
-	// Check if the selector matches the function name
-	if selExpr.Sel.Name != functionName {
-		return false
+// commandRelated returns the related information of the findings on the command document doc: the
+// document, and the call that runs it when the document is written elsewhere.
+func (c *checker) commandRelated(call *ast.CallExpr, fnName string, doc ast.Node) []analysis.RelatedInformation {
+	related := []analysis.RelatedInformation{{Pos: doc.Pos(), End: doc.End(), Message: "the command document"}}
+	if doc.Pos() < call.Pos() || call.End() <= doc.Pos() {
+		related = append(related, analysis.RelatedInformation{Pos: call.Pos(), End: call.End(), Message: "the command is run by " + fnName})
 	}
+	return related
+}
 
-	// Check if the selector's X expression is a package identifier
-	_, ok = selExpr.X.(*ast.Ident)
+// checkCommandFields checks the fields that follow the command name in the command document doc
+// against the limitations of a supported command: the fields it may not carry, including those of the
+// documents of an array field such as the indexes of createIndexes, and the stages of its pipeline,
+// e.g. the pipeline of an aggregate command.
+func (c *checker) checkCommandFields(clients usageClients, related []analysis.RelatedInformation, doc *ast.CompositeLit, name string) {
+	cmd, ok := c.cat.command(name)
 	if !ok {
-		return false
+		return
 	}
-
-	typ := pass.TypesInfo.TypeOf(selExpr.X)
-	if typ == nil {
-		return false
+	for _, limited := range c.limitedFields(doc, cmd.Fields) {
+		d := c.diagnostic(ruleUnstableCommandField, commandFieldDetails(name, limited.field), clients, limited.key.elt.Pos(), "Field %s of command %s is not supported by the MongoDB Stable API", limited.field, name)
+		d.Related = append(append([]analysis.RelatedInformation(nil), related...), d.Related...)
+		c.record(d)
 	}
-
-	typStr := typ.String()
-	if len(typStr) > 0 && typStr[0] == '*' {
-		typStr = typStr[1:]
+	for _, key := range c.docs.literalKeys(doc)[1:] {
+		for _, field := range key.names {
+			if field == cmd.Pipeline && key.value != nil {
+				for _, stage := range c.pipelineStages(key.value, map[ast.Node]bool{}) {
+					for _, stageName := range stage.names {
+						if contains(c.cat.Stages, stageName) {
+							c.report(ruleUnstableStage, c.cat.stageDetails(stageName), clients, stage.expr.Pos(), "Aggregation stage '%s' in command %s is not supported by the MongoDB Stable API", stageName, name)
+							c.reportedStages[stage.expr.Pos()] = true
+						}
+					}
+				}
+			}
+		}
 	}
+}
 
-	if typStr == packagePath {
-		return true
+// isMapType reports whether typ is a map, e.g. bson.M, whose keys are encoded in no defined order.
+func isMapType(typ types.Type) bool {
+	if typ == nil {
+		return false
 	}
+	_, ok := typ.Underlying().(*types.Map)
+	return ok
+}
 
-	return false
+// isBsonDType reports whether typ is bson.D of any driver version. In v1, bson.D is an alias of primitive.D.
+func isBsonDType(cat *Catalog, typ types.Type) bool {
+	return isBsonType(cat, typ, "D")
 }
@@ -0,0 +1,205 @@
+package common
+
+import (
+	"go/ast"
+	"go/types"
+	"slices"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ssa"
+)
+
+// methodCall is a method that a call may call.
+type methodCall struct {
+	fn *types.Func
+	// args are the arguments of the method, without the receiver.
+	args []ast.Expr
+	// via describes how the method is called when it is not named by the call, e.g. "CollectionAPI.Distinct".
+	via string
+}
+
+// calledMethods returns the methods that call may call: the method selected on a value, including
+// the methods promoted from embedded fields, the driver methods that implement a called interface
+// method, and the methods of method values and method expressions.
+func (c *checker) calledMethods(call *ast.CallExpr) []methodCall {
+	if sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+		if selection, ok := c.pass.TypesInfo.Selections[sel]; ok {
+			fn, ok := selection.Obj().(*types.Func)
+			if !ok {
+				return nil
+			}
+			switch selection.Kind() {
+			case types.MethodVal:
+				if isInterfaceMethod(fn) {
+					return c.implementations(c.docs.valueAnalyzer().callees(call), call.Args, interfaceMethodName(fn))
+				}
+				return []methodCall{{fn: fn, args: call.Args}}
+			case types.MethodExpr:
+				// (*mongo.Collection).Watch(coll, ctx, pipeline)
+				if len(call.Args) == 0 {
+					return nil
+				}
+				if isInterfaceMethod(fn) {
+					return c.implementations(c.docs.valueAnalyzer().callees(call), call.Args[1:], interfaceMethodName(fn))
+				}
+				return []methodCall{{fn: fn, args: call.Args[1:], via: "a method expression"}}
+			}
+		}
+	}
+
+	// A function value, e.g. a method value assigned to a variable. Only the calls whose signature
+	// refers to the driver may call a driver method.
+	if isStaticCall(c.pass, call) {
+		return nil
+	}
+	sig, ok := c.pass.TypesInfo.TypeOf(call.Fun).Underlying().(*types.Signature)
+	if !ok || !c.refersToDriver(sig) {
+		return nil
+	}
+
+	var calls []methodCall
+	for _, callee := range c.docs.valueAnalyzer().callees(call) {
+		fn, ok := callee.Object().(*types.Func)
+		if !ok || fn.Type().(*types.Signature).Recv() == nil {
+			continue
+		}
+		// a thunk of a method expression takes the receiver as its first parameter
+		args := call.Args
+		if offset := len(callee.Params) - fn.Type().(*types.Signature).Params().Len(); offset > 0 && offset <= len(args) {
+			args = args[offset:]
+		}
+		if isInterfaceMethod(fn) {
+			// the bound function of an interface method value
+			calls = append(calls, c.implementations(c.docs.valueAnalyzer().wrappedCallees(callee), args, "a function value")...)
+		} else if !containsCall(calls, fn) {
+			calls = append(calls, methodCall{fn: fn, args: args, via: "a function value"})
+		}
+	}
+	return calls
+}
+
+// implementations returns the methods of the driver types that the call of an interface method may
+// call, once each: those of callees, the functions that the VTA call graph resolves the call to, and
+// those that the thunk of a method expression calls. The methods of the other types, and the driver
+// methods whose receivers never reach the call, are left out.
+func (c *checker) implementations(callees []*ssa.Function, args []ast.Expr, via string) []methodCall {
+	var calls []methodCall
+	for _, callee := range callees {
+		fn, ok := callee.Object().(*types.Func)
+		if !ok {
+			continue
+		}
+		if isInterfaceMethod(fn) {
+			for _, call := range c.implementations(c.docs.valueAnalyzer().wrappedCallees(callee), args, via) {
+				if !containsCall(calls, call.fn) {
+					calls = append(calls, call)
+				}
+			}
+		} else if slices.Contains(c.driverMethods()[fn.Name()], fn) && !containsCall(calls, fn) {
+			calls = append(calls, methodCall{fn: fn, args: args, via: via})
+		}
+	}
+	return calls
+}
+
+// containsCall reports whether calls has a call of fn.
+func containsCall(calls []methodCall, fn *types.Func) bool {
+	for _, call := range calls {
+		if call.fn == fn {
+			return true
+		}
+	}
+	return false
+}
+
+// driverMethods returns the methods of the named types of the driver's mongo and options packages
+// that the package imports, directly or not, by method name.
+func (c *checker) driverMethods() map[string][]*types.Func {
+	if c.methods != nil {
+		return c.methods
+	}
+	c.methods = map[string][]*types.Func{}
+
+	for _, pkg := range c.driverPackages() {
+		module := c.cat.driverModule(pkg.Path())
+		if pkg.Path() != module+"/"+mongoPkgName && pkg.Path() != module+"/"+optsPkgName {
+			continue
+		}
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			typeName, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || typeName.IsAlias() || types.IsInterface(typeName.Type()) {
+				continue
+			}
+			methods := types.NewMethodSet(types.NewPointer(typeName.Type()))
+			for i := 0; i < methods.Len(); i++ {
+				if method, ok := methods.At(i).Obj().(*types.Func); ok && method.Exported() {
+					c.methods[method.Name()] = append(c.methods[method.Name()], method)
+				}
+			}
+		}
+	}
+	return c.methods
+}
+
+// refersToDriver reports whether the parameters or results of sig have driver types, as the driver
+// methods that are checked all do, e.g. their options.
+func (c *checker) refersToDriver(sig *types.Signature) bool {
+	for _, tuple := range []*types.Tuple{sig.Params(), sig.Results()} {
+		for i := 0; i < tuple.Len(); i++ {
+			typ := types.Unalias(tuple.At(i).Type())
+			for {
+				if ptr, ok := typ.(*types.Pointer); ok {
+					typ = types.Unalias(ptr.Elem())
+				} else if slice, ok := typ.(*types.Slice); ok {
+					typ = types.Unalias(slice.Elem())
+				} else {
+					break
+				}
+			}
+			if named, ok := typ.(*types.Named); ok && named.Obj().Pkg() != nil && c.cat.driverModule(named.Obj().Pkg().Path()) != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isStaticCall reports whether call calls a declared function or a conversion, rather than a function value.
+func isStaticCall(pass *analysis.Pass, call *ast.CallExpr) bool {
+	if tv, ok := pass.TypesInfo.Types[call.Fun]; !ok || tv.IsType() {
+		return true
+	}
+	var ident *ast.Ident
+	switch fun := astutil.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	case *ast.IndexExpr:
+		// instantiation of a generic function
+		return isStaticCall(pass, &ast.CallExpr{Fun: fun.X})
+	default:
+		return false
+	}
+	switch pass.TypesInfo.Uses[ident].(type) {
+	case *types.Func, *types.Builtin:
+		return true
+	}
+	return false
+}
+
+// isInterfaceMethod reports whether fn is a method of an interface.
+func isInterfaceMethod(fn *types.Func) bool {
+	recv := fn.Type().(*types.Signature).Recv()
+	return recv != nil && types.IsInterface(recv.Type())
+}
+
+// interfaceMethodName returns the name of an interface method with the name of its interface, if it has one.
+func interfaceMethodName(fn *types.Func) string {
+	if name := receiverTypeName(fn); name != "" {
+		return name + "." + fn.Name()
+	}
+	return fn.Name()
+}
@@ -0,0 +1,293 @@
+package common
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Catalog is the set of rules that describes what is outside of the MongoDB Stable API.
+// It is read from YAML; since JSON is a subset of YAML, JSON catalogs work as well.
+type Catalog struct {
+	// Replace discards the default catalog instead of overlaying it.
+	Replace bool `yaml:"replace"`
+
+	// Drivers holds the rules for each major version of the Go driver, keyed by "v1", "v2", ...
+	Drivers map[string]*DriverRules `yaml:"drivers"`
+
+	// Methods lists methods that are not supported, by full package path. Once the catalog is
+	// loaded, it also holds the methods of all Drivers.
+	Methods []SymbolRule `yaml:"methods"`
+
+	// Fields lists options struct fields, and option constants, that are not supported, by full
+	// package path. Once the catalog is loaded, it also holds the fields of all Drivers.
+	Fields []SymbolRule `yaml:"fields"`
+
+	// Stages lists aggregation stages that are not supported.
+	Stages []string `yaml:"stages"`
+
+	// Commands lists the commands that are supported without limitations.
+	Commands []CommandRule `yaml:"commands"`
+
+	// serverVersion is the -server-version the catalog is applied to, nil if unknown.
+	serverVersion serverVersion
+
+	// modelMethods and modelFields are the rules that the command model derives from Commands for
+	// the methods and options of the Drivers.
+	modelMethods []SymbolRule
+	modelFields  []SymbolRule
+}
+
+// DriverRules are the rules for one major version of the Go driver.
+// The packages of its rules are relative to Module, e.g. "mongo/options".
+type DriverRules struct {
+	Module  string       `yaml:"module"`
+	Methods []SymbolRule `yaml:"methods"`
+	Fields  []SymbolRule `yaml:"fields"`
+}
+
+// SymbolRule names members of a type in a driver package, e.g. the Watch method of mongo.Collection.
+// If Since is set, the members are part of the Stable API from those server versions on,
+// and are only flagged when -server-version is older.
+type SymbolRule struct {
+	Package string      `yaml:"package"`
+	Type    string      `yaml:"type"`
+	Names   []string    `yaml:"names"`
+	Since   versionList `yaml:"since"`
+
+	// command is the command that a rule of the command model runs, and field the field of the
+	// command that it sets, if any.
+	command, field string
+}
+
+// CommandRule is a command that is supported by the Stable API, from the Since server versions on
+// if that is set. In the catalog it is either a plain command name or a {name, since, fields, pipeline} mapping.
+// A command that is supported with limitations lists the Fields it may not carry, and the field that holds
+// its Pipeline, whose stages are checked against the unsupported stages.
+type CommandRule struct {
+	Name     string      `yaml:"name"`
+	Since    versionList `yaml:"since"`
+	Fields   []string    `yaml:"fields"`
+	Pipeline string      `yaml:"pipeline"`
+}
+
+// unsupportedOn reports whether the members are not supported on server version v.
+func (r SymbolRule) unsupportedOn(v serverVersion) bool {
+	return len(r.Since) == 0 || !r.Since.includes(v)
+}
+
+func (r *CommandRule) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		r.Name = node.Value
+		return nil
+	}
+	type plain CommandRule
+	return node.Decode((*plain)(r))
+}
+
+//go:embed default_catalog.yaml
+var defaultCatalogYAML []byte
+
+var (
+	catalogPath       string
+	serverVersionFlag string
+)
+
+var (
+	catalogOnce   sync.Once
+	loadedCatalog *Catalog
+	catalogErr    error
+)
+
+func init() {
+	StableAnalyzer.Flags.StringVar(&catalogPath, "catalog", "",
+		"path to a YAML or JSON rule catalog that is overlaid on (or, with \"replace: true\", replaces) the default catalog")
+	StableAnalyzer.Flags.StringVar(&serverVersionFlag, "server-version", "",
+		"oldest MongoDB server version the code runs against, e.g. 5.0.3; catalog entries added to the Stable API later are flagged")
+}
+
+// activeCatalog returns the default catalog combined with the -catalog file, if any.
+// The result is computed once and shared by all packages being analyzed.
+func activeCatalog() (*Catalog, error) {
+	catalogOnce.Do(func() {
+		loadedCatalog, catalogErr = loadCatalog(catalogPath)
+		if catalogErr == nil && serverVersionFlag != "" {
+			loadedCatalog.serverVersion, catalogErr = parseServerVersion(serverVersionFlag)
+		}
+	})
+	return loadedCatalog, catalogErr
+}
+
+func loadCatalog(path string) (*Catalog, error) {
+	cat, err := parseCatalog(defaultCatalogYAML)
+	if err != nil {
+		return nil, fmt.Errorf("default catalog: %v", err)
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		user, err := parseCatalog(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if user.Replace {
+			cat = user
+		} else {
+			cat.overlay(user)
+		}
+	}
+
+	if err := cat.expandDrivers(); err != nil {
+		return nil, err
+	}
+	return cat, nil
+}
+
+func parseCatalog(data []byte) (*Catalog, error) {
+	var cat Catalog
+	if err := yaml.Unmarshal(data, &cat); err != nil {
+		return nil, err
+	}
+	ruleLists := [][]SymbolRule{cat.Methods, cat.Fields}
+	for _, driver := range cat.Drivers {
+		ruleLists = append(ruleLists, driver.Methods, driver.Fields)
+	}
+	for _, rules := range ruleLists {
+		for _, rule := range rules {
+			if rule.Package == "" || rule.Type == "" {
+				return nil, fmt.Errorf("rule for %v needs both a package and a type", rule.Names)
+			}
+		}
+	}
+	for _, cmd := range cat.Commands {
+		if cmd.Name == "" {
+			return nil, fmt.Errorf("command entry without a name")
+		}
+	}
+	return &cat, nil
+}
+
+// overlay adds the rules of other to c.
+func (c *Catalog) overlay(other *Catalog) {
+	for major, driver := range other.Drivers {
+		base, ok := c.Drivers[major]
+		if !ok {
+			if c.Drivers == nil {
+				c.Drivers = map[string]*DriverRules{}
+			}
+			c.Drivers[major] = driver
+			continue
+		}
+		if driver.Module != "" {
+			base.Module = driver.Module
+		}
+		base.Methods = append(base.Methods, driver.Methods...)
+		base.Fields = append(base.Fields, driver.Fields...)
+	}
+	c.Methods = append(c.Methods, other.Methods...)
+	c.Fields = append(c.Fields, other.Fields...)
+	c.Stages = appendMissing(c.Stages, other.Stages...)
+	for _, cmd := range other.Commands {
+		if i := c.commandIndex(cmd.Name); i >= 0 {
+			c.Commands[i] = cmd
+		} else {
+			c.Commands = append(c.Commands, cmd)
+		}
+	}
+}
+
+// expandDrivers adds the rules of each driver to Methods and Fields, with full package paths, and
+// derives the rules of the command model for the driver.
+func (c *Catalog) expandDrivers() error {
+	majors := make([]string, 0, len(c.Drivers))
+	for major := range c.Drivers {
+		majors = append(majors, major)
+	}
+	sort.Strings(majors)
+
+	for _, major := range majors {
+		driver := c.Drivers[major]
+		if driver.Module == "" {
+			return fmt.Errorf("driver %s has no module path", major)
+		}
+		for _, rule := range driver.Methods {
+			rule.Package = driver.Module + "/" + rule.Package
+			c.Methods = append(c.Methods, rule)
+		}
+		for _, rule := range driver.Fields {
+			rule.Package = driver.Module + "/" + rule.Package
+			c.Fields = append(c.Fields, rule)
+		}
+		c.addModelRules(major, driver.Module)
+	}
+	return nil
+}
+
+// driverModule returns the module path of the driver that a package belongs to, or "".
+// Modules are matched by longest prefix, as the v2 module path extends the v1 one.
+func (c *Catalog) driverModule(pkgPath string) string {
+	module := ""
+	for _, driver := range c.Drivers {
+		if (pkgPath == driver.Module || strings.HasPrefix(pkgPath, driver.Module+"/")) && len(driver.Module) > len(module) {
+			module = driver.Module
+		}
+	}
+	return module
+}
+
+func (c *Catalog) commandIndex(name string) int {
+	for i, cmd := range c.Commands {
+		if cmd.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// command returns the catalog entry of a stable command, if any.
+func (c *Catalog) command(name string) (CommandRule, bool) {
+	if i := c.commandIndex(name); i >= 0 {
+		return c.Commands[i], true
+	}
+	return CommandRule{}, false
+}
+
+// versionNote explains a finding that is only due to the -server-version, e.g.
+// " on server version 5.0.3 (added in 5.0.9, 6.0)". It is empty for entries without a Since version.
+func (c *Catalog) versionNote(since versionList) string {
+	if len(since) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" on server version %s (added in %s)", c.serverVersion, since)
+}
+
+// ruleNote explains a finding of a method or field rule: its versionNote, and the command or the field
+// of a command that a rule of the command model is about.
+func (c *Catalog) ruleNote(rule SymbolRule) string {
+	return c.versionNote(rule.Since) + rule.commandNote()
+}
+
+func appendMissing(list []string, values ...string) []string {
+	for _, v := range values {
+		if !contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
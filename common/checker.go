@@ -0,0 +1,110 @@
+package common
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// checker holds the state of the analysis of one package.
+type checker struct {
+	pass   *analysis.Pass
+	cat    *Catalog
+	filter *findingFilter
+	docs   *docAnalyzer
+
+	// results caches what the results of the functions of the package carry.
+	results map[*types.Func]*resultFact
+	// methods holds the methods of the driver types by name, for the calls of interface methods.
+	methods map[string][]*types.Func
+	// symbolTable holds the driver objects the analyzer looks for, built by the first lookup.
+	symbolTable *symbolTable
+	// reportedStages holds the positions of the stages reported in a pipeline passed to the driver,
+	// which are not reported again where a mongo.Pipeline literal is checked on its own.
+	reportedStages map[token.Pos]bool
+
+	diagnostics []recorded
+}
+
+// recorded is a finding recorded by the checks, complete with its fixes, related information and details.
+type recorded struct {
+	Finding
+	// belowSeverity is whether the finding is of usage whose severity is below that of -severity.
+	belowSeverity bool
+}
+
+func newChecker(pass *analysis.Pass, cat *Catalog, filter *findingFilter) *checker {
+	return &checker{pass: pass, cat: cat, filter: filter, docs: newDocAnalyzer(pass, cat), results: map[*types.Func]*resultFact{},
+		reportedStages: map[token.Pos]bool{}}
+}
+
+// report records a diagnostic of unsupported usage for a rule, whose ID is the category of the diagnostic,
+// with the details of what it is about. The severity of the usage depends on the clients that run it.
+// Diagnostics are reported by flush, once all checks have run.
+func (c *checker) report(rule string, details FindingDetails, clients usageClients, pos token.Pos, format string, args ...interface{}) {
+	c.record(c.diagnostic(rule, details, clients, pos, format, args...))
+}
+
+// diagnostic returns the finding of unsupported usage that report records, for the checks that add
+// fixes or related information to it before they record it.
+func (c *checker) diagnostic(rule string, details FindingDetails, clients usageClients, pos token.Pos, format string, args ...interface{}) recorded {
+	d := analysis.Diagnostic{Pos: pos, Category: rule, Message: fmt.Sprintf(format, args...) + c.severityNote(clients), Related: related(clients)}
+	return recorded{Finding: Finding{Diagnostic: d, Severity: clients.severity.String(), Details: details},
+		belowSeverity: clients.severity < c.filter.minSeverity}
+}
+
+// record records the finding d.
+func (c *checker) record(d recorded) {
+	c.diagnostics = append(c.diagnostics, d)
+}
+
+// reportFix records a diagnostic of a rule with a suggested fix, which has no severity.
+func (c *checker) reportFix(rule string, details FindingDetails, pos token.Pos, fix analysis.SuggestedFix, format string, args ...interface{}) {
+	d := analysis.Diagnostic{Pos: pos, Category: rule, Message: fmt.Sprintf(format, args...), SuggestedFixes: []analysis.SuggestedFix{fix}}
+	c.record(recorded{Finding: Finding{Diagnostic: d, Details: details}})
+}
+
+// reportDirective records a diagnostic of a suppression directive, which has no severity.
+func (c *checker) reportDirective(pos token.Pos, fixes []analysis.SuggestedFix, format string, args ...interface{}) {
+	d := analysis.Diagnostic{Pos: pos, Category: ruleSuppression, Message: fmt.Sprintf(format, args...), SuggestedFixes: fixes}
+	c.record(recorded{Finding: Finding{Diagnostic: d, Details: suppressionDetails}})
+}
+
+// flush reports the recorded diagnostics in file and position order, dropping duplicates, those
+// that a directive suppresses and those that the flags leave out. It returns the reported findings.
+func (c *checker) flush() []Finding {
+	c.suppress()
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		pi, pj := c.pass.Fset.Position(c.diagnostics[i].Pos), c.pass.Fset.Position(c.diagnostics[j].Pos)
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Offset < pj.Offset
+	})
+
+	var findings []Finding
+	seen := map[diagnosticKey]bool{}
+	for _, d := range c.diagnostics {
+		k := diagnosticKey{d.Pos, d.Message}
+		if d.belowSeverity || contains(c.filter.excluded, d.Category) {
+			continue
+		}
+		if !seen[k] {
+			seen[k] = true
+			c.pass.Report(d.Diagnostic)
+			findings = append(findings, d.Finding)
+		}
+	}
+	c.diagnostics = nil
+	return findings
+}
+
+// diagnosticKey identifies a recorded diagnostic, whose message tells it apart from the others at its
+// position.
+type diagnosticKey struct {
+	pos     token.Pos
+	message string
+}
@@ -0,0 +1,348 @@
+package common
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// connectFuncs are the functions of the driver's mongo package that make a client from client options.
+var connectFuncs = []string{"Connect", "NewClient"}
+
+// optionValues are the values that options built in one expression set, such as the chain
+// options.Client().ApplyURI(uri).SetServerAPIOptions(api), by setter. The fields set in an options
+// literal count as their setters, e.g. Strict as SetStrict.
+type optionValues struct {
+	// expr is the expression that builds the options, which a fix may extend with another setter.
+	expr ast.Expr
+	// set maps the setters called to the values passed, the last one first.
+	set map[string][]ast.Expr
+}
+
+// checkClients reports the clients made by mongo.Connect or mongo.NewClient whose options do not
+// request the Stable API, request it without strict mode, or without deprecation errors.
+func (c *checker) checkClients(inspect *inspector.Inspector) {
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node) {
+		call := node.(*ast.CallExpr)
+		fn, ok := typeutil.Callee(c.pass.TypesInfo, call).(*types.Func)
+		if !ok || !c.symbols().connects[fn] || call.Ellipsis.IsValid() {
+			return
+		}
+		// the client options are the variadic parameter
+		sig := fn.Type().(*types.Signature)
+		first := sig.Params().Len() - 1
+		if !sig.Variadic() || len(call.Args) < first {
+			return
+		}
+
+		if len(call.Args) == first {
+			opts, edits := c.optionsQualifier(call, fn)
+			text := opts + "Client().SetServerAPIOptions(" + strictServerAPI(opts) + ")"
+			if first > 0 {
+				text = ", " + text
+			}
+			fix := analysis.SuggestedFix{
+				Message:   "Request the strict Stable API V1",
+				TextEdits: append(edits, analysis.TextEdit{Pos: call.Rparen, End: call.Rparen, NewText: []byte(text)}),
+			}
+			c.reportFix(ruleClientConfig, clientDetails(fn), call.Pos(), fix,
+				"The client made by %s.%s does not request the MongoDB Stable API, set it with SetServerAPIOptions", fn.Pkg().Name(), fn.Name())
+			return
+		}
+
+		clientOpts, serverAPIs, ok := c.clientOptions(call.Args[first:])
+		if !ok {
+			return
+		}
+		if len(serverAPIs) == 0 {
+			last := clientOpts[len(clientOpts)-1]
+			opts, edits := c.optionsQualifier(last.expr, fn)
+			fix := c.extendFix(last.expr, ".SetServerAPIOptions("+strictServerAPI(opts)+")")
+			fix.TextEdits = append(edits, fix.TextEdits...)
+			c.reportFix(ruleClientConfig, clientDetails(fn), call.Pos(), fix,
+				"The client made by %s.%s does not request the MongoDB Stable API, set it with SetServerAPIOptions", fn.Pkg().Name(), fn.Name())
+			return
+		}
+
+		for _, serverAPI := range serverAPIs {
+			apiOpts, ok := c.optionValues(serverAPI, "ServerAPIOptions")
+			if !ok {
+				continue
+			}
+			for _, opts := range apiOpts {
+				c.checkServerAPI(call, fn, opts)
+			}
+		}
+	})
+}
+
+// clientOptions returns what the client options args set, and the Stable API options they set. It is
+// false if the construction of some options is not known.
+func (c *checker) clientOptions(args []ast.Expr) ([]optionValues, []ast.Expr, bool) {
+	var clientOpts []optionValues
+	for _, arg := range args {
+		values, ok := c.optionValues(arg, "ClientOptions")
+		if !ok {
+			return nil, nil, false
+		}
+		clientOpts = append(clientOpts, values...)
+	}
+	// The options are merged in order, so the last options that set the Stable API win
+	var serverAPIs []ast.Expr
+	for _, opts := range clientOpts {
+		if set := opts.set["SetServerAPIOptions"]; len(set) > 0 {
+			serverAPIs = append(serverAPIs, set[0])
+		}
+	}
+	return clientOpts, serverAPIs, true
+}
+
+// connectSeverity returns the severity of the unsupported usage run by the client that call, a call
+// of mongo.Connect or mongo.NewClient, makes: an error if it requests the strict Stable API, which
+// rejects the usage, and a warning if it requests the Stable API without strict mode or does not
+// request it.
+func (c *checker) connectSeverity(call *ast.CallExpr, fn *types.Func) severity {
+	first := fn.Type().(*types.Signature).Params().Len() - 1
+	if call.Ellipsis.IsValid() || len(call.Args) < first {
+		return severityUnknown
+	}
+	_, serverAPIs, ok := c.clientOptions(call.Args[first:])
+	if !ok {
+		return severityUnknown
+	}
+	if len(serverAPIs) == 0 {
+		return severityWarning
+	}
+
+	sev := severityError
+	for _, serverAPI := range serverAPIs {
+		apiOpts, ok := c.optionValues(serverAPI, "ServerAPIOptions")
+		if !ok {
+			return severityUnknown
+		}
+		for _, opts := range apiOpts {
+			strict := opts.set["SetStrict"]
+			if len(strict) == 0 {
+				sev = min(sev, severityWarning)
+			} else if value, ok := c.boolValue(strict[0]); !ok {
+				return severityUnknown
+			} else if !value {
+				sev = min(sev, severityWarning)
+			}
+		}
+	}
+	return sev
+}
+
+// checkServerAPI reports the Stable API options of a client that are not strict or do not report deprecations.
+func (c *checker) checkServerAPI(call *ast.CallExpr, fn *types.Func, opts optionValues) {
+	strict := opts.set["SetStrict"]
+	if len(strict) == 0 {
+		c.reportFix(ruleClientConfig, clientDetails(fn), call.Pos(), c.extendFix(opts.expr, ".SetStrict(true)"),
+			"The client made by %s.%s does not set the MongoDB Stable API to strict, set it with SetStrict(true)", fn.Pkg().Name(), fn.Name())
+	} else if value, ok := c.boolValue(strict[0]); ok && !value {
+		fix := analysis.SuggestedFix{
+			Message:   "Set the Stable API to strict",
+			TextEdits: []analysis.TextEdit{{Pos: strict[0].Pos(), End: strict[0].End(), NewText: []byte("true")}},
+		}
+		c.reportFix(ruleClientConfig, clientDetails(fn), strict[0].Pos(), fix,
+			"The client made by %s.%s sets the MongoDB Stable API to non-strict, unsupported usage is not rejected by the server", fn.Pkg().Name(), fn.Name())
+	}
+
+	deprecations := opts.set["SetDeprecationErrors"]
+	if len(deprecations) == 0 {
+		c.reportFix(ruleClientConfig, clientDetails(fn), call.Pos(), c.extendFix(opts.expr, ".SetDeprecationErrors(true)"),
+			"The client made by %s.%s does not set deprecation errors on the MongoDB Stable API, set them with SetDeprecationErrors(true)", fn.Pkg().Name(), fn.Name())
+	} else if value, ok := c.boolValue(deprecations[0]); ok && !value {
+		fix := analysis.SuggestedFix{
+			Message:   "Report deprecations as errors",
+			TextEdits: []analysis.TextEdit{{Pos: deprecations[0].Pos(), End: deprecations[0].End(), NewText: []byte("true")}},
+		}
+		c.reportFix(ruleClientConfig, clientDetails(fn), deprecations[0].Pos(), fix,
+			"The client made by %s.%s turns off deprecation errors on the MongoDB Stable API", fn.Pkg().Name(), fn.Name())
+	}
+}
+
+// optionValues returns what the options of type typeName that expr may stand for set, for each of
+// their constructions: a chain of setters on options.Client() or options.ServerAPI(...), or a literal,
+// with the setters called on the variable that holds them. It is false if a construction is not known,
+// e.g. options returned by a function of another package.
+func (c *checker) optionValues(expr ast.Expr, typeName string) ([]optionValues, bool) {
+	var stmtSets map[string][]ast.Expr
+	if ident, ok := astutil.Unparen(expr).(*ast.Ident); ok {
+		if obj, ok := c.pass.TypesInfo.Uses[ident].(*types.Var); ok {
+			stmtSets = c.settersOn(obj, ident)
+		}
+	}
+
+	candidates := c.docs.resolve(expr, map[ast.Node]bool{})
+	if len(candidates) == 0 {
+		return nil, false
+	}
+	var all []optionValues
+	for _, x := range candidates {
+		values := optionValues{expr: x, set: map[string][]ast.Expr{}}
+		if !c.chainValues(x, typeName, values.set) {
+			return nil, false
+		}
+		for setter, args := range stmtSets {
+			values.set[setter] = append(append([]ast.Expr(nil), args...), values.set[setter]...)
+		}
+		all = append(all, values)
+	}
+	return all, true
+}
+
+// chainValues adds the values that the setters of a chain set to set, down to the function that
+// makes the options or the options literal. It is false if the chain does not start there.
+func (c *checker) chainValues(expr ast.Expr, typeName string, set map[string][]ast.Expr) bool {
+	expr = astutil.Unparen(expr)
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		expr = astutil.Unparen(u.X)
+	}
+
+	switch x := expr.(type) {
+	case *ast.CompositeLit:
+		if !isDriverType(c.cat, c.pass.TypesInfo.TypeOf(x), optsPkgName, typeName) {
+			return false
+		}
+		for _, elt := range x.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				if ident, ok := kv.Key.(*ast.Ident); ok {
+					set["Set"+ident.Name] = append(set["Set"+ident.Name], kv.Value)
+				}
+			}
+		}
+		return true
+
+	case *ast.CallExpr:
+		fn, ok := typeutil.Callee(c.pass.TypesInfo, x).(*types.Func)
+		if !ok || !isDriverType(c.cat, c.pass.TypesInfo.TypeOf(x), optsPkgName, typeName) {
+			return false
+		}
+		if fn.Type().(*types.Signature).Recv() == nil {
+			// options.Client() or options.ServerAPI(version)
+			return fn.Pkg() != nil && fn.Pkg().Path() == c.cat.driverModule(fn.Pkg().Path())+"/"+optsPkgName
+		}
+		sel, ok := astutil.Unparen(x.Fun).(*ast.SelectorExpr)
+		if !ok {
+			return false
+		}
+		if len(x.Args) == 1 {
+			set[fn.Name()] = append(set[fn.Name()], x.Args[0])
+		}
+		return c.chainValues(sel.X, typeName, set)
+	}
+	return false
+}
+
+// settersOn returns the values that the statements of the function that declares the options
+// variable obj set on it, by setter, e.g. opts.SetServerAPIOptions(api) or opts.ServerAPIOptions = api.
+func (c *checker) settersOn(obj *types.Var, use *ast.Ident) map[string][]ast.Expr {
+	set := map[string][]ast.Expr{}
+	body := c.docs.enclosingBody(use)
+	if body == nil {
+		return set
+	}
+	isVar := func(expr ast.Expr) bool {
+		ident, ok := astutil.Unparen(expr).(*ast.Ident)
+		return ok && c.pass.TypesInfo.Uses[ident] == obj
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ExprStmt:
+			// a setter called for its effect on the options
+			if call, ok := astutil.Unparen(n.X).(*ast.CallExpr); ok && len(call.Args) == 1 {
+				if sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr); ok && isVar(sel.X) {
+					set[sel.Sel.Name] = append([]ast.Expr{call.Args[0]}, set[sel.Sel.Name]...)
+				}
+			}
+		case *ast.AssignStmt:
+			if len(n.Lhs) != len(n.Rhs) {
+				return true
+			}
+			for i, lhs := range n.Lhs {
+				if sel, ok := astutil.Unparen(lhs).(*ast.SelectorExpr); ok && isVar(sel.X) {
+					set["Set"+sel.Sel.Name] = append([]ast.Expr{n.Rhs[i]}, set["Set"+sel.Sel.Name]...)
+				}
+			}
+		}
+		return true
+	})
+	return set
+}
+
+// boolValue returns the value of a boolean expression, or of the constant a pointer points to, e.g. &strict.
+func (c *checker) boolValue(expr ast.Expr) (bool, bool) {
+	expr = astutil.Unparen(expr)
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		expr = u.X
+	}
+	for _, x := range append([]ast.Expr{expr}, c.docs.resolve(expr, map[ast.Node]bool{})...) {
+		if tv, ok := c.pass.TypesInfo.Types[x]; ok && tv.Value != nil && tv.Value.Kind() == constant.Bool {
+			return constant.BoolVal(tv.Value), true
+		}
+	}
+	return false, false
+}
+
+// strictServerAPI returns the expression of strict Stable API V1 options, with the qualifier opts of the options package.
+func strictServerAPI(opts string) string {
+	return opts + "ServerAPI(" + opts + "ServerAPIVersion1).SetStrict(true)"
+}
+
+// optionsQualifier returns the name under which the file of node imports the options package of the
+// driver of fn, followed by a dot. If the file does not import it, the name is options and the edits
+// add the import.
+func (c *checker) optionsQualifier(node ast.Node, fn *types.Func) (string, []analysis.TextEdit) {
+	return c.importQualifier(node, c.cat.driverModule(fn.Pkg().Path())+"/"+optsPkgName, "options")
+}
+
+// importQualifier returns the name under which the file of node imports the package path, followed by
+// a dot. If the file does not import it, the name is name and the edits add the import.
+func (c *checker) importQualifier(node ast.Node, path, name string) (string, []analysis.TextEdit) {
+	for _, file := range c.pass.Files {
+		if file.Pos() > node.Pos() || node.Pos() >= file.End() {
+			continue
+		}
+		for _, spec := range file.Imports {
+			if importPath, err := strconv.Unquote(spec.Path.Value); err == nil && importPath == path {
+				if spec.Name != nil {
+					return spec.Name.Name + ".", nil
+				}
+				return name + ".", nil
+			}
+		}
+		// add the import to the first import declaration, or after the package clause
+		for _, decl := range file.Decls {
+			if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT && gen.Lparen.IsValid() {
+				return name + ".", []analysis.TextEdit{{Pos: gen.Rparen, End: gen.Rparen, NewText: []byte("\t" + strconv.Quote(path) + "\n")}}
+			}
+		}
+		return name + ".", []analysis.TextEdit{{Pos: file.Name.End(), End: file.Name.End(), NewText: []byte("\n\nimport " + strconv.Quote(path))}}
+	}
+	return name + ".", nil
+}
+
+// extendFix returns the fix that calls the setter text on the options built by expr, adding parentheses
+// around a literal.
+func (c *checker) extendFix(expr ast.Expr, text string) analysis.SuggestedFix {
+	fix := analysis.SuggestedFix{Message: fmt.Sprintf("Call %s on the options", text[1:])}
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		fix.TextEdits = []analysis.TextEdit{
+			{Pos: expr.Pos(), End: expr.Pos(), NewText: []byte("(")},
+			{Pos: expr.End(), End: expr.End(), NewText: []byte(")" + text)},
+		}
+	} else {
+		fix.TextEdits = []analysis.TextEdit{{Pos: expr.End(), End: expr.End(), NewText: []byte(text)}}
+	}
+	return fix
+}
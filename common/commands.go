@@ -0,0 +1,246 @@
+package common
+
+import (
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// The command model maps the driver methods to the server commands that they run, and the options
+// of the driver to the fields of the commands that they set, e.g. Collection.EstimatedDocumentCount
+// to count and FindOptions.SetMax to the max field of find. The methods and options are classified
+// from the commands section of the catalog, which RunCommand is checked against too, so that a
+// command is supported or not whichever way it is run. The methods and fields sections of the
+// catalog list the usage that the commands do not describe, e.g. change streams.
+
+// methodCommands are the server commands that the methods of the driver types run, by type and
+// method. The methods that run no command, e.g. Collection.Name, are left out. The commands of the
+// cursors that the methods return, getMore and killCursors, and the hello commands with which a client
+// monitors the servers are not listed.
+var methodCommands = map[string]map[string][]string{
+	"Client": {
+		"BulkWrite":         {"bulkWrite"},
+		"Disconnect":        {"endSessions"},
+		"ListDatabaseNames": {"listDatabases"},
+		"ListDatabases":     {"listDatabases"},
+		"Ping":              {"ping"},
+		"Watch":             {"aggregate"},
+	},
+	"Database": {
+		"Aggregate":                    {"aggregate"},
+		"CreateCollection":             {"create"},
+		"CreateView":                   {"create"},
+		"Drop":                         {"dropDatabase"},
+		"ListCollectionNames":          {"listCollections"},
+		"ListCollectionSpecifications": {"listCollections"},
+		"ListCollections":              {"listCollections"},
+		"Watch":                        {"aggregate"},
+	},
+	"Collection": {
+		"Aggregate":              {"aggregate"},
+		"BulkWrite":              {"insert", "update", "delete"},
+		"CountDocuments":         {"aggregate"},
+		"DeleteMany":             {"delete"},
+		"DeleteOne":              {"delete"},
+		"Distinct":               {"distinct"},
+		"Drop":                   {"drop"},
+		"EstimatedDocumentCount": {"count"},
+		"Find":                   {"find"},
+		"FindOne":                {"find"},
+		"FindOneAndDelete":       {"findAndModify"},
+		"FindOneAndReplace":      {"findAndModify"},
+		"FindOneAndUpdate":       {"findAndModify"},
+		"InsertMany":             {"insert"},
+		"InsertOne":              {"insert"},
+		"ReplaceOne":             {"update"},
+		"UpdateByID":             {"update"},
+		"UpdateMany":             {"update"},
+		"UpdateOne":              {"update"},
+		"Watch":                  {"aggregate"},
+	},
+	"IndexView": {
+		"CreateMany":         {"createIndexes"},
+		"CreateOne":          {"createIndexes"},
+		"DropAll":            {"dropIndexes"},
+		"DropOne":            {"dropIndexes"},
+		"DropWithKey":        {"dropIndexes"},
+		"List":               {"listIndexes"},
+		"ListSpecifications": {"listIndexes"},
+	},
+	"SearchIndexView": {
+		"CreateMany": {"createSearchIndexes"},
+		"CreateOne":  {"createSearchIndexes"},
+		"DropOne":    {"dropSearchIndex"},
+		"List":       {"aggregate"},
+		"UpdateOne":  {"updateSearchIndex"},
+	},
+}
+
+// commandField is a field of a command, e.g. the max field of the find command. A field of the
+// documents of an array field is named by its path, e.g. indexes.sparse.
+type commandField struct {
+	command, field string
+}
+
+// optionFields maps the options of the driver, by options type and option, to the field of the
+// command that they set. An option is set by the field of its name of the options struct, and by its
+// setter, e.g. SetMax. The options that set no field that a command may not carry are left out.
+var optionFields = map[string]map[string]commandField{
+	"CreateCollectionOptions": {
+		"Capped":              {"create", "capped"},
+		"DefaultIndexOptions": {"create", "indexOptionDefaults"},
+		"MaxDocuments":        {"create", "max"},
+		"SizeInBytes":         {"create", "size"},
+		"StorageEngine":       {"create", "storageEngine"},
+	},
+	"FindOneOptions": {
+		"Max":             {"find", "max"},
+		"Min":             {"find", "min"},
+		"NoCursorTimeout": {"find", "noCursorTimeout"},
+		"OplogReplay":     {"find", "oplogReplay"},
+		"ReturnKey":       {"find", "returnKey"},
+		"ShowRecordID":    {"find", "showRecordId"},
+	},
+	"FindOptions": {
+		"CursorType":      {"find", "tailable"},
+		"Max":             {"find", "max"},
+		"Min":             {"find", "min"},
+		"NoCursorTimeout": {"find", "noCursorTimeout"},
+		"OplogReplay":     {"find", "oplogReplay"},
+		"ReturnKey":       {"find", "returnKey"},
+		"ShowRecordID":    {"find", "showRecordId"},
+	},
+	"IndexOptions": {
+		"Background":    {"createIndexes", "indexes.background"},
+		"BucketSize":    {"createIndexes", "indexes.bucketSize"},
+		"Sparse":        {"createIndexes", "indexes.sparse"},
+		"StorageEngine": {"createIndexes", "indexes.storageEngine"},
+	},
+}
+
+// addModelRules adds the rules of the command model for the driver of major version major and module:
+// the methods that run a command that is not supported, or only from the Since versions of the
+// command, and the options that set a field that their command may not carry. The setters of the
+// options are on the options types in v1, and on their builders from v2 on.
+func (c *Catalog) addModelRules(major, module string) {
+	for _, typeName := range sortedKeys(methodCommands) {
+		methods := methodCommands[typeName]
+		for _, name := range sortedKeys(methods) {
+			if rule, ok := c.methodRule(methods[name]); ok {
+				rule.Package, rule.Type, rule.Names = module+"/"+mongoPkgName, typeName, []string{name}
+				c.modelMethods = append(c.modelMethods, rule)
+			}
+		}
+	}
+	for _, typeName := range sortedKeys(optionFields) {
+		options := optionFields[typeName]
+		for _, name := range sortedKeys(options) {
+			field := options[name]
+			if cmd, ok := c.command(field.command); !ok || !contains(cmd.Fields, field.field) {
+				continue
+			}
+			pkg, setterType := module+"/"+optsPkgName, typeName
+			if major != "v1" {
+				setterType += "Builder"
+			}
+			c.modelMethods = append(c.modelMethods, SymbolRule{Package: pkg, Type: setterType, Names: []string{"Set" + name},
+				command: field.command, field: field.field})
+			c.modelFields = append(c.modelFields, SymbolRule{Package: pkg, Type: typeName, Names: []string{name},
+				command: field.command, field: field.field})
+		}
+	}
+}
+
+// methodRule returns the rule of a method that runs commands, if one of them is not supported: a
+// command that the catalog does not list, or else one that it lists from some server versions on.
+func (c *Catalog) methodRule(commands []string) (SymbolRule, bool) {
+	var since SymbolRule
+	for _, name := range commands {
+		cmd, ok := c.command(name)
+		if !ok {
+			return SymbolRule{command: name}, true
+		}
+		if len(cmd.Since) > 0 && since.command == "" {
+			since = SymbolRule{Since: cmd.Since, command: name}
+		}
+	}
+	return since, since.command != ""
+}
+
+// methodRules returns the rules of the methods: those of the catalog, then those of the command model.
+func (c *Catalog) methodRules() []SymbolRule {
+	return append(append([]SymbolRule(nil), c.Methods...), c.modelMethods...)
+}
+
+// fieldRules returns the rules of the fields: those of the catalog, then those of the command model.
+func (c *Catalog) fieldRules() []SymbolRule {
+	return append(append([]SymbolRule(nil), c.Fields...), c.modelFields...)
+}
+
+// commandNote explains a finding of a rule of the command model by the command or the field of the
+// command, e.g. ", as it runs the distinct command". It is empty for the rules of the catalog.
+func (r SymbolRule) commandNote() string {
+	switch {
+	case r.field != "":
+		return ", as the option is the field " + r.field + " of the " + r.command + " command"
+	case r.command != "":
+		return ", as it runs the " + r.command + " command"
+	}
+	return ""
+}
+
+// entry returns the entry of the catalog of the member name of a rule of section, the methods or
+// fields section: for a rule of the command model, the entry of its command in the commands section.
+func (r SymbolRule) entry(section, name string) CatalogEntry {
+	if r.command != "" {
+		return CatalogEntry{Section: "commands", Name: r.command, Field: r.field, Since: versionStrings(r.Since)}
+	}
+	return CatalogEntry{Section: section, Package: r.Package, Type: r.Type, Name: name, Since: versionStrings(r.Since)}
+}
+
+// limitedField is a field of a command document that its command may not carry.
+type limitedField struct {
+	// field is the field of the catalog, e.g. indexes.sparse, and key the key of the document that is it.
+	field string
+	key   docKey
+}
+
+// limitedFields returns the fields of the command document doc, after its command name, that are
+// among fields: the fields of the document, and those of the documents of an array field that a
+// path names, e.g. the sparse field of the indexes of a createIndexes command.
+func (c *checker) limitedFields(doc *ast.CompositeLit, fields []string) []limitedField {
+	var limited []limitedField
+	for _, key := range c.docs.literalKeys(doc)[1:] {
+		for _, field := range fields {
+			parent, child, nested := strings.Cut(field, ".")
+			if !contains(key.names, parent) {
+				continue
+			}
+			if !nested {
+				limited = append(limited, limitedField{field, key})
+				continue
+			}
+			if key.value == nil {
+				continue
+			}
+			seen := map[ast.Node]bool{}
+			for _, elt := range c.docs.elements(key.value, seen) {
+				for _, sub := range c.docs.documentKeys(elt, seen) {
+					if contains(sub.names, child) {
+						limited = append(limited, limitedField{field, sub})
+					}
+				}
+			}
+		}
+	}
+	return limited
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,190 @@
+package common
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Categories of the findings, for reports. They are finer than the rules: the cursor type constants
+// are checked by the rule of the options fields.
+const (
+	CategoryMethod       = "method"
+	CategoryStructField  = "struct-field"
+	CategoryAggStage     = "agg-stage"
+	CategoryCommand      = "command"
+	CategoryCursorType   = "cursor-type"
+	CategoryClientConfig = "client-config"
+	CategorySuppression  = "suppression"
+)
+
+// FindingDetails are what a report tells of a finding besides its message.
+type FindingDetails struct {
+	Category string
+	// Symbol is what the finding is about: a driver method or field such as mongo.Collection.Distinct,
+	// a stage, a command, or the driver function that runs a command or makes a client.
+	Symbol string
+	// Entry is the entry of the catalog that the finding matched, nil if there is none, e.g. for a
+	// command that the catalog does not list as supported.
+	Entry       *CatalogEntry
+	Remediation string
+}
+
+// CatalogEntry is one name of an entry of the catalog.
+type CatalogEntry struct {
+	// Section is methods, fields, stages or commands.
+	Section string
+	// Package is the full path of the package of a method or field, and Type its type.
+	Package string
+	Type    string
+	Name    string
+	// Field is the field that a command of the commands section may not carry.
+	Field string
+	// Since are the server versions from which the entry is part of the Stable API.
+	Since []string
+}
+
+// Finding is a diagnostic that the analyzer reports, with what a report tells of it besides its message.
+type Finding struct {
+	analysis.Diagnostic
+	// Severity is error, warning or unknown for unsupported usage, after the clients that run it, and ""
+	// for the findings on the clients themselves and on the suppression directives.
+	Severity string
+	Details  FindingDetails
+}
+
+// symbolDetails returns the details of a finding on name, a method, an options field or a cursor type
+// constant of the driver that rule is of.
+func symbolDetails(rule SymbolRule, category, name string) FindingDetails {
+	section := "fields"
+	if category == CategoryMethod {
+		section = "methods"
+	}
+	entry := rule.entry(section, name)
+	return FindingDetails{
+		Category:    category,
+		Symbol:      rule.Package[strings.LastIndex(rule.Package, "/")+1:] + "." + rule.Type + "." + name,
+		Entry:       &entry,
+		Remediation: symbolRemediation(category, rule.Type, name) + sinceNote(entry.Since),
+	}
+}
+
+// stageDetails returns the details of a finding on the aggregation stage name.
+func (c *Catalog) stageDetails(name string) FindingDetails {
+	d := FindingDetails{Category: CategoryAggStage, Symbol: name, Remediation: stageRemediation(name)}
+	if contains(c.Stages, name) {
+		d.Entry = &CatalogEntry{Section: "stages", Name: name}
+	}
+	return d
+}
+
+// commandDetails returns the details of a finding on the command name, which has an entry if the
+// catalog lists it as supported from a later server version.
+func (c *Catalog) commandDetails(name string) FindingDetails {
+	d := FindingDetails{Category: CategoryCommand, Symbol: name, Remediation: commandRemediation(name)}
+	if cmd, ok := c.command(name); ok {
+		d.Entry = &CatalogEntry{Section: "commands", Name: cmd.Name, Since: versionStrings(cmd.Since)}
+		d.Remediation += sinceNote(d.Entry.Since)
+	}
+	return d
+}
+
+// commandFieldDetails returns the details of a finding on the field of the command name.
+func commandFieldDetails(name, field string) FindingDetails {
+	return FindingDetails{
+		Category:    CategoryCommand,
+		Symbol:      name + "." + field,
+		Entry:       &CatalogEntry{Section: "commands", Name: name, Field: field},
+		Remediation: fmt.Sprintf("Remove the field %s from the %s command; it is not supported by the Stable API", field, name),
+	}
+}
+
+// runDetails returns the details of a finding of rule, the command unresolved or unordered, on the
+// command document passed to the driver method fnName.
+func runDetails(rule, fnName string) FindingDetails {
+	d := FindingDetails{Category: CategoryCommand, Symbol: fnName,
+		Remediation: "Review the command against the commands of the Stable API, or write the command document as a bson.D at the call so that it is checked"}
+	if rule == ruleUnorderedCommand {
+		d.Remediation = "Write the command document as a bson.D, whose first element is the command"
+	}
+	return d
+}
+
+// clientDetails returns the details of a finding on the client made by fn, e.g. mongo.Connect.
+func clientDetails(fn *types.Func) FindingDetails {
+	return FindingDetails{Category: CategoryClientConfig, Symbol: fn.Pkg().Name() + "." + fn.Name(),
+		Remediation: "Request the Stable API V1 in strict mode with deprecation errors: " +
+			"SetServerAPIOptions(options.ServerAPI(options.ServerAPIVersion1).SetStrict(true).SetDeprecationErrors(true))"}
+}
+
+// suppressionDetails are the details of the findings on the suppression directives.
+var suppressionDetails = FindingDetails{Category: CategorySuppression,
+	Remediation: "Give the reason of the suppression after --, name the rule of the finding, or remove the directive"}
+
+func versionStrings(since versionList) []string {
+	var versions []string
+	for _, v := range since {
+		versions = append(versions, v.String())
+	}
+	return versions
+}
+
+// sinceNote tells that an entry is part of the Stable API from later server versions.
+func sinceNote(since []string) string {
+	if len(since) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("; it is part of the Stable API from server version %s", strings.Join(since, ", "))
+}
+
+// notStrict is the way out for usage that has no supported replacement.
+const notStrict = "run it on a client that does not request the strict Stable API"
+
+// methodRemediations are the supported replacements of the unsupported driver methods.
+var methodRemediations = map[string]string{
+	"Distinct":               "Run an aggregation with a $group stage on the field instead",
+	"EstimatedDocumentCount": "Use CountDocuments instead",
+	"Watch":                  "Change streams are not part of the Stable API; " + notStrict,
+	"SearchIndexes":          "Atlas Search indexes are not part of the Stable API; " + notStrict,
+}
+
+// symbolRemediation returns the remediation of an unsupported method, options field or cursor type.
+func symbolRemediation(category, typeName, name string) string {
+	switch {
+	case category == CategoryCursorType:
+		return "Use the default non-tailable cursor, or " + notStrict
+	case category == CategoryStructField:
+		return fmt.Sprintf("Leave the option %s of %s unset", name, typeName)
+	case strings.HasPrefix(name, "Set") && strings.Contains(typeName, "Options"):
+		return fmt.Sprintf("Remove the call of %s; the option is not supported by the Stable API", name)
+	}
+	if remediation, ok := methodRemediations[name]; ok {
+		return remediation
+	}
+	return "Use a method of the Stable API instead, or " + notStrict
+}
+
+// stageRemediation returns the remediation of an unsupported aggregation stage.
+func stageRemediation(stage string) string {
+	if stage == "$search" {
+		return "Atlas Search is not part of the Stable API; " + notStrict
+	}
+	return fmt.Sprintf("Remove the stage %s from the pipeline, or %s", stage, notStrict)
+}
+
+// commandRemediations are the supported replacements of the unsupported commands.
+var commandRemediations = map[string]string{
+	"count":     "Use Collection.CountDocuments instead",
+	"distinct":  "Use Collection.Aggregate with a $group stage on the key instead",
+	"mapReduce": "Use an aggregation pipeline instead",
+}
+
+// commandRemediation returns the remediation of an unsupported command.
+func commandRemediation(name string) string {
+	if remediation, ok := commandRemediations[name]; ok {
+		return remediation
+	}
+	return "Use a driver method or a command of the Stable API instead, or " + notStrict
+}
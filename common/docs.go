@@ -0,0 +1,430 @@
+package common
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// docKey is one key of a BSON document written in the source, e.g. "$match" in bson.D{{"$match", filter}}.
+type docKey struct {
+	// names are the values the key may have, none if they could not be determined.
+	names []string
+	// elt is the element of the document, and expr the key expression within it. Both are used for positions.
+	elt  ast.Expr
+	expr ast.Expr
+	// value is the value expression, nil if unknown.
+	value ast.Expr
+}
+
+// varDef is a definition of a variable: an assignment, a declaration or a range clause.
+type varDef struct {
+	// node is the node of the control flow graph that defines the variable: the assignment,
+	// the ValueSpec, or the value of the range clause.
+	node  ast.Node
+	pos   token.Pos
+	scope ast.Node // enclosing function body, or the file for package level variables
+	rhs   ast.Expr
+	// rangeOver is set when the variable is the value of a range clause over that expression.
+	rangeOver ast.Expr
+}
+
+// docAnalyzer extracts documents and arrays, e.g. aggregation pipelines, from the expressions
+// passed to the driver, following variables back to their definitions.
+type docAnalyzer struct {
+	pass *analysis.Pass
+	cat  *Catalog
+	defs map[types.Object][]varDef
+
+	// bodies are the bodies of the functions of the package, function literals included.
+	bodies []*ast.BlockStmt
+	// flows holds the control flow graphs of the bodies, built on first use.
+	flows map[*ast.BlockStmt]*flowGraph
+	// built holds the types of the document and array literals that stand for a variable
+	// built up with append or map updates, which are not in the source.
+	built map[*ast.CompositeLit]types.Type
+
+	// values is built on first use, e.g. by the first key that is not a constant.
+	values *valueAnalyzer
+}
+
+func newDocAnalyzer(pass *analysis.Pass, cat *Catalog) *docAnalyzer {
+	a := &docAnalyzer{
+		pass:  pass,
+		cat:   cat,
+		defs:  map[types.Object][]varDef{},
+		flows: map[*ast.BlockStmt]*flowGraph{},
+		built: map[*ast.CompositeLit]types.Type{},
+	}
+	for _, file := range pass.Files {
+		a.collectDefs(file, file)
+	}
+	return a
+}
+
+// collectDefs records the variable definitions in node, which belongs to scope.
+func (a *docAnalyzer) collectDefs(node ast.Node, scope ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n != node && n.Body != nil {
+				a.bodies = append(a.bodies, n.Body)
+				a.collectDefs(n.Body, n.Body)
+				return false
+			}
+		case *ast.FuncLit:
+			if n != node {
+				a.bodies = append(a.bodies, n.Body)
+				a.collectDefs(n.Body, n.Body)
+				return false
+			}
+		case *ast.AssignStmt:
+			if len(n.Lhs) == len(n.Rhs) {
+				for i, lhs := range n.Lhs {
+					a.addDef(lhs, varDef{node: n, pos: n.Pos(), scope: scope, rhs: n.Rhs[i]})
+				}
+			} else if len(n.Rhs) == 1 {
+				// the first result of a call, e.g. raw in raw, err := bson.Marshal(cmd)
+				a.addDef(n.Lhs[0], varDef{node: n, pos: n.Pos(), scope: scope, rhs: n.Rhs[0]})
+			}
+		case *ast.ValueSpec:
+			if len(n.Names) == len(n.Values) {
+				for i, name := range n.Names {
+					a.addDef(name, varDef{node: n, pos: n.Pos(), scope: scope, rhs: n.Values[i]})
+				}
+			} else if len(n.Values) == 1 {
+				a.addDef(n.Names[0], varDef{node: n, pos: n.Pos(), scope: scope, rhs: n.Values[0]})
+			}
+		case *ast.RangeStmt:
+			if n.Value != nil {
+				a.addDef(n.Value, varDef{node: n.Value, pos: n.Pos(), scope: scope, rangeOver: n.X})
+			}
+		}
+		return true
+	})
+}
+
+func (a *docAnalyzer) addDef(lhs ast.Expr, def varDef) {
+	ident, ok := astutil.Unparen(lhs).(*ast.Ident)
+	if !ok {
+		return
+	}
+	obj := a.pass.TypesInfo.ObjectOf(ident)
+	if obj == nil {
+		return
+	}
+	a.defs[obj] = append(a.defs[obj], def)
+}
+
+// resolve returns the expressions that a variable use may stand for. Within a function, those are the
+// definitions that reach the use through the function's control flow, with the elements that appends
+// and map updates add on the way; otherwise all definitions are candidates. Other expressions are
+// returned as they are, except for the calls of append, which stand for the literals they build.
+// seen holds the definitions being resolved, which a variable built up in a loop reaches again.
+func (a *docAnalyzer) resolve(expr ast.Expr, seen map[ast.Node]bool) []ast.Expr {
+	expr = astutil.Unparen(expr)
+	if expr == nil {
+		return nil
+	}
+	if call, ok := expr.(*ast.CallExpr); ok && a.isBuiltin(call, "append") {
+		return a.appended(call, seen)
+	}
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return []ast.Expr{expr}
+	}
+	obj, ok := a.pass.TypesInfo.Uses[ident].(*types.Var)
+	if !ok {
+		return nil
+	}
+
+	defs, updates := a.reachingDefs(obj, ident)
+	var exprs []ast.Expr
+	for _, def := range defs {
+		if seen[def.node] {
+			continue
+		}
+		seen[def.node] = true
+		if def.rangeOver != nil {
+			for _, x := range a.resolve(def.rangeOver, seen) {
+				exprs = append(exprs, a.elements(x, seen)...)
+			}
+		} else {
+			exprs = append(exprs, a.resolve(def.rhs, seen)...)
+		}
+		delete(seen, def.node)
+	}
+	if len(updates) > 0 {
+		exprs = a.updated(exprs, updates)
+	}
+	return exprs
+}
+
+// typeOf returns the type of expr, which may be a literal built up with append or map updates.
+func (a *docAnalyzer) typeOf(expr ast.Expr) types.Type {
+	if lit, ok := expr.(*ast.CompositeLit); ok {
+		if typ, ok := a.built[lit]; ok {
+			return typ
+		}
+	}
+	return a.pass.TypesInfo.TypeOf(expr)
+}
+
+// isBuiltin reports whether call calls the builtin function name, e.g. append.
+func (a *docAnalyzer) isBuiltin(call *ast.CallExpr, name string) bool {
+	ident, ok := astutil.Unparen(call.Fun).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	builtin, ok := a.pass.TypesInfo.Uses[ident].(*types.Builtin)
+	return ok && builtin.Name() == name
+}
+
+// resolveMarshaled is resolve, looking through the encoding of documents to BSON bytes: the calls
+// of bson.Marshal and its variants, and the conversions to bson.Raw. The document literals that
+// expr may stand for are returned without their & operator.
+func (a *docAnalyzer) resolveMarshaled(expr ast.Expr, seen map[ast.Node]bool) []ast.Expr {
+	var exprs []ast.Expr
+	for _, x := range a.resolve(expr, seen) {
+		if u, ok := x.(*ast.UnaryExpr); ok && u.Op == token.AND {
+			exprs = append(exprs, a.resolveMarshaled(u.X, seen)...)
+			continue
+		}
+		if call, ok := x.(*ast.CallExpr); ok && len(call.Args) > 0 {
+			if tv, ok := a.pass.TypesInfo.Types[call.Fun]; ok && tv.IsType() && isBsonType(a.cat, tv.Type, "Raw") {
+				exprs = append(exprs, a.resolveMarshaled(call.Args[0], seen)...)
+				continue
+			}
+			if a.isMarshal(call) {
+				// the document is the last argument of all variants
+				exprs = append(exprs, a.resolveMarshaled(call.Args[len(call.Args)-1], seen)...)
+				continue
+			}
+		}
+		exprs = append(exprs, x)
+	}
+	return exprs
+}
+
+// isMarshal reports whether call calls a function of the bson package of any driver version that
+// encodes a value to BSON bytes: Marshal, MarshalAppend and their WithRegistry and WithContext variants.
+func (a *docAnalyzer) isMarshal(call *ast.CallExpr) bool {
+	fn, ok := typeutil.Callee(a.pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Type().(*types.Signature).Recv() != nil {
+		return false
+	}
+	pkgPath := fn.Pkg().Path()
+	if module := a.cat.driverModule(pkgPath); module == "" || pkgPath != module+"/"+bsonPkgName {
+		return false
+	}
+	switch fn.Name() {
+	case "Marshal", "MarshalAppend", "MarshalWithRegistry", "MarshalWithContext",
+		"MarshalAppendWithRegistry", "MarshalAppendWithContext":
+		return true
+	}
+	return false
+}
+
+// stringValues returns the values that a string expression may have: its value if it is a constant,
+// otherwise the values found through SSA.
+func (a *docAnalyzer) stringValues(expr ast.Expr) []string {
+	if tv, ok := a.pass.TypesInfo.Types[astutil.Unparen(expr)]; ok && tv.Value != nil {
+		if tv.Value.Kind() == constant.String {
+			return []string{constant.StringVal(tv.Value)}
+		}
+		return nil
+	}
+	return a.valueAnalyzer().stringValues(expr)
+}
+
+// valueAnalyzer returns the SSA analysis of the package, building it on first use.
+func (a *docAnalyzer) valueAnalyzer() *valueAnalyzer {
+	if a.values == nil {
+		a.values = newValueAnalyzer(a.pass)
+	}
+	return a.values
+}
+
+// documents returns the document literals that expr may stand for.
+// Documents are bson.D, bson.M, other maps with string keys and structs.
+func (a *docAnalyzer) documents(expr ast.Expr, seen map[ast.Node]bool) []*ast.CompositeLit {
+	var docs []*ast.CompositeLit
+	for _, x := range a.resolve(expr, seen) {
+		if u, ok := x.(*ast.UnaryExpr); ok && u.Op == token.AND {
+			x = astutil.Unparen(u.X)
+		}
+		if lit, ok := x.(*ast.CompositeLit); ok && a.isDocumentType(a.typeOf(lit)) {
+			docs = append(docs, lit)
+		}
+	}
+	return docs
+}
+
+// documentKeys returns the keys of the documents that expr may stand for, in order.
+func (a *docAnalyzer) documentKeys(expr ast.Expr, seen map[ast.Node]bool) []docKey {
+	var keys []docKey
+	for _, doc := range a.documents(expr, seen) {
+		keys = append(keys, a.literalKeys(doc)...)
+	}
+	return keys
+}
+
+// literalKeys returns the keys of a document literal, in order: the keys of its elements, or the
+// keys of the fields that the driver encodes for a struct.
+func (a *docAnalyzer) literalKeys(doc *ast.CompositeLit) []docKey {
+	if a.isStructDocument(a.typeOf(doc)) {
+		return a.structKeys(doc)
+	}
+	var keys []docKey
+	for _, elt := range doc.Elts {
+		keys = append(keys, a.elementKey(elt))
+	}
+	return keys
+}
+
+// elementKey returns the key of one element of a document literal: a key/value pair of a map,
+// or a bson.E of a bson.D, keyed or not.
+func (a *docAnalyzer) elementKey(elt ast.Expr) docKey {
+	key := docKey{elt: elt}
+	if kv, ok := elt.(*ast.KeyValueExpr); ok {
+		// map element
+		key.expr, key.value = kv.Key, kv.Value
+	} else if e, ok := astutil.Unparen(elt).(*ast.CompositeLit); ok {
+		// bson.E element
+		for i, field := range e.Elts {
+			if kv, ok := field.(*ast.KeyValueExpr); ok {
+				if ident, ok := kv.Key.(*ast.Ident); ok {
+					switch ident.Name {
+					case "Key":
+						key.expr = kv.Value
+					case "Value":
+						key.value = kv.Value
+					}
+				}
+			} else if i == 0 {
+				key.expr = field
+			} else if i == 1 {
+				key.value = field
+			}
+		}
+	}
+
+	if key.expr == nil {
+		key.expr = elt
+	} else {
+		key.names = a.stringValues(key.expr)
+	}
+	return key
+}
+
+// elements returns the element expressions of the arrays that expr may stand for,
+// e.g. the stages of a mongo.Pipeline.
+func (a *docAnalyzer) elements(expr ast.Expr, seen map[ast.Node]bool) []ast.Expr {
+	var elts []ast.Expr
+	for _, x := range a.resolve(expr, seen) {
+		lit, ok := x.(*ast.CompositeLit)
+		if !ok || !a.isArrayType(a.typeOf(lit)) {
+			continue
+		}
+		for _, elt := range lit.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				// indexed array element
+				elt = kv.Value
+			}
+			elts = append(elts, elt)
+		}
+	}
+	return elts
+}
+
+// isDocumentType reports whether typ is encoded as a BSON document: bson.D, bson.M, a map with string keys,
+// or a struct.
+func (a *docAnalyzer) isDocumentType(typ types.Type) bool {
+	if typ == nil {
+		return false
+	}
+	if isBsonType(a.cat, typ, "D") || isBsonType(a.cat, typ, "M") || a.isStructDocument(typ) {
+		return true
+	}
+	m, ok := typ.Underlying().(*types.Map)
+	if !ok {
+		return false
+	}
+	basic, ok := m.Key().Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.String
+}
+
+// isArrayType reports whether typ is encoded as a BSON array: bson.A, mongo.Pipeline or any other
+// slice or array that is not a bson.D.
+func (a *docAnalyzer) isArrayType(typ types.Type) bool {
+	if typ == nil || isBsonType(a.cat, typ, "D") {
+		return false
+	}
+	switch typ.Underlying().(type) {
+	case *types.Slice, *types.Array:
+		return true
+	}
+	return false
+}
+
+// isBsonType reports whether typ is the named type of the bson package of any driver version,
+// e.g. bson.D, or an alias of it such as type Doc = bson.D. In v1 the bson types are aliases of the
+// primitive package types.
+func isBsonType(cat *Catalog, typ types.Type, name string) bool {
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok || named.Obj().Name() != name || named.Obj().Pkg() == nil {
+		return false
+	}
+	pkgPath := named.Obj().Pkg().Path()
+	module := cat.driverModule(pkgPath)
+	return module != "" && (pkgPath == module+"/"+bsonPkgName || pkgPath == module+"/"+primitivePkgName)
+}
+
+// isDriverType reports whether typ, or what it points to, is the named type pkg.name of any driver version,
+// where pkg is relative to the driver module, e.g. mongo.Collection, through aliases.
+func isDriverType(cat *Catalog, typ types.Type, pkg, name string) bool {
+	if ptr, ok := types.Unalias(typ).(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok || named.Obj().Name() != name || named.Obj().Pkg() == nil {
+		return false
+	}
+	pkgPath := named.Obj().Pkg().Path()
+	return pkgPath == cat.driverModule(pkgPath)+"/"+pkg
+}
+
+// calledMethod returns the method called by call, whatever the receiver expression is, or nil.
+func calledMethod(pass *analysis.Pass, call *ast.CallExpr) *types.Func {
+	sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	selection, ok := pass.TypesInfo.Selections[sel]
+	if !ok || selection.Kind() != types.MethodVal {
+		return nil
+	}
+	fn, _ := selection.Obj().(*types.Func)
+	return fn
+}
+
+// receiverTypeName returns the name of the named type a method is declared on, e.g. "Collection".
+func receiverTypeName(fn *types.Func) string {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return ""
+	}
+	typ := recv.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if named, ok := typ.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+	return ""
+}
@@ -0,0 +1,424 @@
+package common
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// Kinds of findings carried by function results
+const (
+	optionFinding = "option"
+	stageFinding  = "stage"
+)
+
+// resultFact is exported for the functions whose results carry usage that is not supported by the
+// Stable API: options with unsupported setters or fields, or pipelines with restricted stages. The
+// usage is reported where another package passes such a result to the driver. The fact also holds
+// the commands of the returned documents, for RunCommand.
+type resultFact struct {
+	Findings []resultFinding
+	Commands []resultCommand
+}
+
+func (*resultFact) AFact() {}
+
+func (f *resultFact) String() string {
+	var messages []string
+	for _, finding := range f.Findings {
+		messages = append(messages, finding.Message)
+	}
+	for _, cmd := range f.Commands {
+		messages = append(messages, "command "+cmd.Name)
+	}
+	return "result: " + strings.Join(messages, "; ")
+}
+
+// resultFinding is one unsupported usage in the result of a function.
+type resultFinding struct {
+	Kind string
+	// Rule is the ID of the rule of the usage.
+	Rule    string
+	Message string
+	Details FindingDetails
+	// Package is the path of the package where the usage is, and Origin its position in that package.
+	Package string
+	Origin  string
+}
+
+// resultCommand is the command of a returned document, supported or not.
+type resultCommand struct {
+	Name   string
+	Origin string
+}
+
+// exportResultFacts exports a resultFact for each function of the package whose results carry unsupported
+// usage or commands.
+func (c *checker) exportResultFacts() {
+	for _, file := range c.pass.Files {
+		for _, decl := range file.Decls {
+			if decl, ok := decl.(*ast.FuncDecl); ok {
+				if fn, ok := c.pass.TypesInfo.Defs[decl.Name].(*types.Func); ok {
+					c.resultOf(fn)
+				}
+			}
+		}
+	}
+}
+
+// resultOf returns what the results of fn carry, fn being a function of this package or an imported
+// one, and exports the fact for the functions of this package.
+func (c *checker) resultOf(fn *types.Func) *resultFact {
+	if fn.Pkg() != c.pass.Pkg {
+		fact := &resultFact{}
+		c.pass.ImportObjectFact(fn, fact)
+		return fact
+	}
+
+	if fact, ok := c.results[fn]; ok {
+		return fact
+	}
+	fact := &resultFact{}
+	// recursive functions see nothing in their own results
+	c.results[fn] = fact
+
+	decl := c.funcDecl(fn)
+	if decl == nil || decl.Body == nil {
+		return fact
+	}
+
+	results := fn.Type().(*types.Signature).Results()
+	hasOptions := false
+	for i := 0; i < results.Len(); i++ {
+		if isOptionsType(c.cat, results.At(i).Type()) {
+			hasOptions = true
+		}
+	}
+	if hasOptions {
+		fact.Findings = append(fact.Findings, c.optionFindings(fn, decl)...)
+	}
+
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			if len(n.Results) != results.Len() {
+				return true
+			}
+			for i, result := range n.Results {
+				c.addDocumentResults(fact, result, results.At(i).Type())
+			}
+		}
+		return true
+	})
+
+	if len(fact.Findings) > 0 || len(fact.Commands) > 0 {
+		c.pass.ExportObjectFact(fn, fact)
+	}
+	return fact
+}
+
+// addDocumentResults adds to fact the restricted stages of a returned pipeline or stage document,
+// and the command of a returned command document.
+func (c *checker) addDocumentResults(fact *resultFact, expr ast.Expr, typ types.Type) {
+	if !c.isPipelineType(typ) && !c.docs.isDocumentType(typ) {
+		return
+	}
+
+	for _, stage := range c.pipelineStages(expr, map[ast.Node]bool{}) {
+		for _, name := range stage.names {
+			if contains(c.cat.Stages, name) {
+				fact.Findings = append(fact.Findings, c.finding(stageFinding, ruleUnstableStage, c.cat.stageDetails(name), stage.expr.Pos(),
+					fmt.Sprintf("Aggregation stage '%s' is not supported by the MongoDB Stable API", name)))
+			}
+		}
+	}
+
+	if c.docs.isDocumentType(typ) {
+		for _, doc := range c.docs.documents(expr, map[ast.Node]bool{}) {
+			key, ok := c.commandKey(doc)
+			if !ok {
+				continue
+			}
+			for _, name := range key.names {
+				fact.Commands = append(fact.Commands, resultCommand{Name: name, Origin: c.origin(key.elt.Pos())})
+			}
+		}
+	}
+
+	// results of other functions returned as they are
+	for _, x := range c.docs.resolve(expr, map[ast.Node]bool{}) {
+		if call, ok := x.(*ast.CallExpr); ok {
+			if callee := typeutil.StaticCallee(c.pass.TypesInfo, call); callee != nil {
+				result := c.resultOf(callee)
+				for _, finding := range result.Findings {
+					if finding.Kind != optionFinding {
+						fact.Findings = append(fact.Findings, finding)
+					}
+				}
+				fact.Commands = append(fact.Commands, result.Commands...)
+			}
+		}
+	}
+}
+
+// optionFindings returns the unsupported setters and fields used on the options that fn returns,
+// following the options back through the setters, which return their receiver.
+func (c *checker) optionFindings(fn *types.Func, decl *ast.FuncDecl) []resultFinding {
+	ssaFn := c.docs.valueAnalyzer().pkg.Prog.FuncValue(fn)
+	if ssaFn == nil {
+		return nil
+	}
+
+	var instrs []ssa.Instruction
+	opts := map[ssa.Value]bool{}
+	for _, block := range ssaFn.Blocks {
+		for _, instr := range block.Instrs {
+			instrs = append(instrs, instr)
+			if ret, ok := instr.(*ssa.Return); ok {
+				for _, v := range ret.Results {
+					if isOptionsType(c.cat, v.Type()) {
+						opts[v] = true
+					}
+				}
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, instr := range instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok || !isOptionsMethod(c.cat, call.Call.Signature()) || len(call.Call.Args) == 0 {
+				continue
+			}
+			recv := call.Call.Args[0]
+			if opts[call] && !opts[recv] {
+				opts[recv], changed = true, true
+			}
+			if opts[recv] && !opts[call] && isOptionsType(c.cat, call.Type()) {
+				opts[call], changed = true, true
+			}
+		}
+	}
+
+	starts := nodeStarts(decl)
+	start := func(pos token.Pos) token.Pos {
+		if p, ok := starts[pos]; ok {
+			return p
+		}
+		return pos
+	}
+
+	var findings []resultFinding
+	for _, instr := range instrs {
+		switch instr := instr.(type) {
+		case *ssa.Call:
+			callee := instr.Call.StaticCallee()
+			if callee != nil && isOptionsMethod(c.cat, callee.Signature) && len(instr.Call.Args) > 0 && opts[instr.Call.Args[0]] {
+				if rule, ok := c.symbols().rule(callee.Object()); ok {
+					findings = append(findings, c.finding(optionFinding, ruleUnstableMethod, symbolDetails(rule, CategoryMethod, callee.Name()), start(instr.Pos()),
+						fmt.Sprintf("Function %v.%v is not supported by the MongoDB Stable API%s", rule.Type, callee.Name(), c.cat.ruleNote(rule))))
+				}
+			} else if opts[instr] && callee != nil {
+				if obj, ok := callee.Object().(*types.Func); ok {
+					for _, finding := range c.resultOf(obj).Findings {
+						if finding.Kind == optionFinding {
+							findings = append(findings, finding)
+						}
+					}
+				}
+			}
+
+		case *ssa.Store:
+			field, ok := instr.Addr.(*ssa.FieldAddr)
+			if !ok || !opts[field.X] {
+				continue
+			}
+			fieldVar := field.X.Type().Underlying().(*types.Pointer).Elem().Underlying().(*types.Struct).Field(field.Field)
+			if rule, ok := c.symbols().rule(fieldVar); ok {
+				findings = append(findings, c.finding(optionFinding, ruleUnstableField, symbolDetails(rule, CategoryStructField, fieldVar.Name()), start(instr.Pos()),
+					fmt.Sprintf("Struct field %s.%s is not supported by the MongoDB Stable API%s", rule.Type, fieldVar.Name(), c.cat.ruleNote(rule))))
+			}
+		}
+	}
+	return findings
+}
+
+// checkResultFacts reports the unsupported usage carried by the results of functions of other packages,
+// where they are passed to the driver.
+func (c *checker) checkResultFacts(inspect *inspector.Inspector) {
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node) {
+		call := node.(*ast.CallExpr)
+		fn, ok := typeutil.Callee(c.pass.TypesInfo, call).(*types.Func)
+		if !ok || fn.Pkg() == nil || fn.Pkg().Path() != c.cat.driverModule(fn.Pkg().Path())+"/"+mongoPkgName {
+			return
+		}
+		pipelineArg, isPipeline := c.symbols().pipelines[fn]
+
+		for i, arg := range call.Args {
+			kinds := []string{optionFinding}
+			if isPipeline && i == pipelineArg {
+				kinds = append(kinds, stageFinding)
+			}
+
+			for _, producer := range c.resultProducers(arg) {
+				for _, finding := range c.resultOf(producer).Findings {
+					if finding.Package != c.pass.Pkg.Path() && contains(kinds, finding.Kind) {
+						c.report(finding.Rule, finding.Details, c.receiverClients(c.callReceiver(call)), arg.Pos(), "%s, in the result of %s (%s)", finding.Message, funcName(producer), finding.Origin)
+					}
+				}
+			}
+		}
+	})
+}
+
+// resultProducers returns the functions whose results expr may stand for, through variables and
+// the setters called on options.
+func (c *checker) resultProducers(expr ast.Expr) []*types.Func {
+	var funcs []*types.Func
+	for _, x := range c.docs.resolve(expr, map[ast.Node]bool{}) {
+		if call, ok := x.(*ast.CallExpr); ok {
+			if fn := c.resultProducer(call); fn != nil {
+				funcs = append(funcs, fn)
+			}
+		}
+	}
+	return funcs
+}
+
+// resultProducer returns the function whose result call stands for, if it calls a declared function.
+// A setter called on options stands for the options it is called on, e.g. options.Find().SetLimit(5)
+// for the result of options.Find().
+func (c *checker) resultProducer(call *ast.CallExpr) *types.Func {
+	for {
+		method := calledMethod(c.pass, call)
+		if method == nil || !isOptionsMethod(c.cat, method.Type().(*types.Signature)) {
+			break
+		}
+		sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr)
+		if !ok {
+			break
+		}
+		recv, ok := astutil.Unparen(sel.X).(*ast.CallExpr)
+		if !ok {
+			break
+		}
+		call = recv
+	}
+	return typeutil.StaticCallee(c.pass.TypesInfo, call)
+}
+
+// finding returns a finding of this package at pos.
+func (c *checker) finding(kind, rule string, details FindingDetails, pos token.Pos, message string) resultFinding {
+	return resultFinding{Kind: kind, Rule: rule, Message: message, Details: details, Package: c.pass.Pkg.Path(), Origin: c.origin(pos)}
+}
+
+// origin returns pos as the package path, the file name, the line and the column, which is
+// the same whichever directory the package is analyzed from.
+func (c *checker) origin(pos token.Pos) string {
+	position := c.pass.Fset.Position(pos)
+	return fmt.Sprintf("%s/%s:%d:%d", c.pass.Pkg.Path(), filepath.Base(position.Filename), position.Line, position.Column)
+}
+
+// commandMessage returns the message for an unsupported command, if name is one.
+func (c *checker) commandMessage(name string) (string, bool) {
+	cmd, ok := c.cat.command(name)
+	if !ok {
+		return fmt.Sprintf("Command %s is not supported by the MongoDB Stable API", name), true
+	}
+	if !cmd.Since.includes(c.cat.serverVersion) {
+		return fmt.Sprintf("Command %s is not supported by the MongoDB Stable API%s", name, c.cat.versionNote(cmd.Since)), true
+	}
+	return "", false
+}
+
+// funcDecl returns the declaration of a function of this package.
+func (c *checker) funcDecl(fn *types.Func) *ast.FuncDecl {
+	for _, file := range c.pass.Files {
+		if file.Pos() <= fn.Pos() && fn.Pos() < file.End() {
+			for _, decl := range file.Decls {
+				if decl, ok := decl.(*ast.FuncDecl); ok && decl.Name.Pos() == fn.Pos() {
+					return decl
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isPipelineType reports whether typ may be returned as a pipeline or a stage: mongo.Pipeline, bson.A,
+// a bson.D or bson.M, or a slice of them.
+func (c *checker) isPipelineType(typ types.Type) bool {
+	if isDriverType(c.cat, typ, mongoPkgName, "Pipeline") || isBsonType(c.cat, typ, "A") {
+		return true
+	}
+	if slice, ok := typ.(*types.Slice); ok {
+		typ = slice.Elem()
+	}
+	return isBsonType(c.cat, typ, "D") || isBsonType(c.cat, typ, "M")
+}
+
+// isOptionsMethod reports whether sig is the signature of a method of an options type, such as a setter.
+func isOptionsMethod(cat *Catalog, sig *types.Signature) bool {
+	return sig.Recv() != nil && isOptionsType(cat, sig.Recv().Type())
+}
+
+// isOptionsType reports whether typ is a pointer to a type of the options package of any driver version,
+// e.g. *options.FindOptions or *options.FindOptionsBuilder.
+func isOptionsType(cat *Catalog, typ types.Type) bool {
+	_, _, ok := optionsTypeName(cat, typ)
+	return ok
+}
+
+// optionsTypeName returns the package path and the name of the options type that typ points to.
+func optionsTypeName(cat *Catalog, typ types.Type) (string, string, bool) {
+	ptr, ok := types.Unalias(typ).(*types.Pointer)
+	if !ok {
+		return "", "", false
+	}
+	named, ok := types.Unalias(ptr.Elem()).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return "", "", false
+	}
+	pkgPath := named.Obj().Pkg().Path()
+	if pkgPath != cat.driverModule(pkgPath)+"/"+optsPkgName {
+		return "", "", false
+	}
+	return pkgPath, named.Obj().Name(), true
+}
+
+// funcName returns the name of a function as it is written in the calling package, e.g. dbutil.DefaultFindOptions.
+func funcName(fn *types.Func) string {
+	if recv := receiverTypeName(fn); recv != "" {
+		return fn.Pkg().Name() + "." + recv + "." + fn.Name()
+	}
+	return fn.Pkg().Name() + "." + fn.Name()
+}
+
+// nodeStarts maps the positions that SSA instructions carry, such as the parenthesis of a call or the
+// colon of a composite literal element, to the start of their expression in decl.
+func nodeStarts(decl *ast.FuncDecl) map[token.Pos]token.Pos {
+	starts := map[token.Pos]token.Pos{}
+	ast.Inspect(decl, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			starts[n.Lparen] = n.Pos()
+		case *ast.KeyValueExpr:
+			starts[n.Colon] = n.Key.Pos()
+		case *ast.AssignStmt:
+			starts[n.TokPos] = n.Pos()
+		}
+		return true
+	})
+	return starts
+}
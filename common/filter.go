@@ -0,0 +1,74 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	severityFlag string
+	excludeFlag  string
+)
+
+var (
+	filterOnce   sync.Once
+	loadedFilter *findingFilter
+	filterErr    error
+)
+
+func init() {
+	StableAnalyzer.Flags.StringVar(&severityFlag, "severity", "",
+		"lowest severity of the unsupported usage that is reported: unknown (the default, all usage), warning or error; findings on clients and directives are always reported")
+	StableAnalyzer.Flags.StringVar(&excludeFlag, "exclude", "",
+		"comma-separated IDs of the rules whose findings are not reported, e.g. GS005-unresolved-command")
+}
+
+// findingFilter drops the findings that the -severity and -exclude flags leave out. It applies after
+// the suppression directives, so that a directive of a dropped finding is not reported as unused.
+type findingFilter struct {
+	minSeverity severity
+	excluded    []string
+}
+
+// activeFilter returns the filter of the flags, which is computed once for all packages.
+func activeFilter() (*findingFilter, error) {
+	filterOnce.Do(func() {
+		loadedFilter, filterErr = parseFilter(severityFlag, excludeFlag)
+	})
+	return loadedFilter, filterErr
+}
+
+func parseFilter(minSeverity, exclude string) (*findingFilter, error) {
+	f := &findingFilter{}
+	switch minSeverity {
+	case "", "unknown":
+		f.minSeverity = severityUnknown
+	case "warning":
+		f.minSeverity = severityWarning
+	case "error":
+		f.minSeverity = severityError
+	default:
+		return nil, fmt.Errorf("-severity: unknown severity %q, want unknown, warning or error", minSeverity)
+	}
+	for _, rule := range strings.Split(exclude, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		if !contains(ruleIDs, rule) {
+			return nil, fmt.Errorf("-exclude: unknown rule %s", rule)
+		}
+		f.excluded = append(f.excluded, rule)
+	}
+	return f, nil
+}
+
+// CheckFlags returns the error of the flags of the analyzer, such as a catalog that cannot be read,
+// which is otherwise returned by the analysis of each package.
+func CheckFlags() error {
+	if _, err := activeCatalog(); err != nil {
+		return err
+	}
+	_, err := activeFilter()
+	return err
+}
@@ -0,0 +1,320 @@
+package common
+
+import (
+	"bytes"
+	"go/ast"
+	"go/constant"
+	"go/format"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// Suggested fixes for the findings that have a mechanical rewrite in the Stable API. A fix is only
+// suggested when the rewritten code type checks and runs the same expressions, so the arguments that
+// it moves or repeats must not have side effects.
+
+// removableOptions are the options that a fix removes, by field name, as the server ignores them or
+// they are not needed: a setter such as SetNoCursorTimeout is deleted from its chain or statement,
+// and a field such as Background from its options literal.
+var removableOptions = []string{"Background", "NoCursorTimeout", "OplogReplay", "ShowRecordID"}
+
+// methodFix returns the fix of a call of the unsupported method fn, if there is one: Collection.Distinct
+// is rewritten as an aggregation, and the setters of removableOptions are deleted. stack is the path to call.
+func (c *checker) methodFix(call *ast.CallExpr, fn *types.Func, stack []ast.Node) (analysis.SuggestedFix, bool) {
+	if fn.Name() == "Distinct" && isDriverType(c.cat, fn.Type().(*types.Signature).Recv().Type(), mongoPkgName, "Collection") {
+		return c.distinctFix(call, fn)
+	}
+	if strings.HasPrefix(fn.Name(), "Set") && contains(removableOptions, strings.TrimPrefix(fn.Name(), "Set")) {
+		return c.setterFix(call, fn, stack)
+	}
+	return analysis.SuggestedFix{}, false
+}
+
+// distinctFix rewrites coll.Distinct(ctx, field, filter) as a function literal that runs an aggregation
+// with $match, $unwind and $group stages and returns the distinct values, as Distinct does in v1 of
+// the driver. The call must not pass options.
+func (c *checker) distinctFix(call *ast.CallExpr, fn *types.Func) (analysis.SuggestedFix, bool) {
+	sig := fn.Type().(*types.Signature)
+	if sig.Results().Len() != 2 || !types.Identical(sig.Results().At(0).Type(), types.NewSlice(types.NewInterfaceType(nil, nil))) {
+		return analysis.SuggestedFix{}, false
+	}
+	sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || len(call.Args) != 3 || call.Ellipsis.IsValid() || !c.pure(sel.X) {
+		return analysis.SuggestedFix{}, false
+	}
+	ctx, field, filter := call.Args[0], call.Args[1], call.Args[2]
+	// the function literal declares these names, which the arguments must not use
+	for _, arg := range call.Args {
+		if !c.pure(arg) || usesNames(arg, "cursor", "err", "docs", "values", "i", "doc") {
+			return analysis.SuggestedFix{}, false
+		}
+	}
+
+	fieldPath := `"$" + ` + c.nodeText(field)
+	if tv := c.pass.TypesInfo.Types[field]; tv.Value != nil && tv.Value.Kind() == constant.String {
+		fieldPath = strconv.Quote("$" + constant.StringVal(tv.Value))
+	}
+	module := c.cat.driverModule(fn.Pkg().Path())
+	mongo, edits := c.importQualifier(call, fn.Pkg().Path(), "mongo")
+	bson, more := c.importQualifier(call, module+"/"+bsonPkgName, "bson")
+
+	text := "func() ([]interface{}, error) {\n" +
+		"cursor, err := " + c.nodeText(sel.X) + ".Aggregate(" + c.nodeText(ctx) + ", " + mongo + "Pipeline{\n" +
+		"{{Key: \"$match\", Value: " + c.nodeText(filter) + "}},\n" +
+		"{{Key: \"$unwind\", Value: " + fieldPath + "}},\n" +
+		"{{Key: \"$group\", Value: " + bson + "D{{Key: \"_id\", Value: " + fieldPath + "}}}},\n" +
+		"})\n" +
+		"if err != nil {\nreturn nil, err\n}\n" +
+		"var docs []" + bson + "M\n" +
+		"if err := cursor.All(" + c.nodeText(ctx) + ", &docs); err != nil {\nreturn nil, err\n}\n" +
+		"values := make([]interface{}, len(docs))\n" +
+		"for i, doc := range docs {\nvalues[i] = doc[\"_id\"]\n}\n" +
+		"return values, nil\n" +
+		"}()"
+	edits = append(append(edits, more...), analysis.TextEdit{Pos: call.Pos(), End: call.End(), NewText: []byte(text)})
+	return analysis.SuggestedFix{Message: "Run an aggregation with a $group stage instead of Distinct", TextEdits: edits}, true
+}
+
+// countFix rewrites db.RunCommand(ctx, bson.D{{"count", coll}, {"query", filter}}), with the command
+// document written at the call, as a function literal that calls CountDocuments on the collection and
+// returns its count in a single result, as the count command does.
+func (c *checker) countFix(call *ast.CallExpr, fn *types.Func, doc *ast.CompositeLit) (analysis.SuggestedFix, bool) {
+	recv := c.callReceiver(call)
+	if recv == nil || fn.Name() != "RunCommand" || !isDriverType(c.cat, c.pass.TypesInfo.TypeOf(recv), mongoPkgName, "Database") ||
+		len(call.Args) != 2 || astutil.Unparen(call.Args[1]) != doc || !isBsonDType(c.cat, c.docs.typeOf(doc)) {
+		return analysis.SuggestedFix{}, false
+	}
+	var coll, query ast.Expr
+	for i, key := range c.docs.literalKeys(doc) {
+		if len(key.names) != 1 || key.value == nil {
+			return analysis.SuggestedFix{}, false
+		}
+		switch {
+		case i == 0 && key.names[0] == "count":
+			coll = key.value
+		case i > 0 && key.names[0] == "query" && query == nil:
+			query = key.value
+		default:
+			return analysis.SuggestedFix{}, false
+		}
+	}
+	if coll == nil || !c.pure(recv, call.Args[0], coll) || query != nil && !c.pure(query) {
+		return analysis.SuggestedFix{}, false
+	}
+
+	module := c.cat.driverModule(fn.Pkg().Path())
+	mongo, edits := c.importQualifier(call, fn.Pkg().Path(), "mongo")
+	bson, more := c.importQualifier(call, module+"/"+bsonPkgName, "bson")
+	filter := bson + "D{}"
+	if query != nil {
+		filter = c.nodeText(query)
+	}
+
+	text := "func() *" + mongo + "SingleResult {\n" +
+		"n, err := " + c.nodeText(recv) + ".Collection(" + c.nodeText(coll) + ").CountDocuments(" + c.nodeText(call.Args[0]) + ", " + filter + ")\n" +
+		"return " + mongo + "NewSingleResultFromDocument(" + bson + "D{{Key: \"n\", Value: n}, {Key: \"ok\", Value: 1}}, err, nil)\n" +
+		"}()"
+	edits = append(append(edits, more...), analysis.TextEdit{Pos: call.Pos(), End: call.End(), NewText: []byte(text)})
+	return analysis.SuggestedFix{Message: "Call CountDocuments instead of the count command", TextEdits: edits}, true
+}
+
+// setterFix deletes the call of a setter of removableOptions: from its chain, e.g. options.Find().SetNoCursorTimeout(true),
+// or, when it is called on a variable for its effect, the statement.
+func (c *checker) setterFix(call *ast.CallExpr, fn *types.Func, stack []ast.Node) (analysis.SuggestedFix, bool) {
+	sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || len(call.Args) != 1 || !c.pure(call.Args[0]) {
+		return analysis.SuggestedFix{}, false
+	}
+	fix := analysis.SuggestedFix{Message: "Remove the call of " + fn.Name()}
+
+	if len(stack) >= 2 {
+		if stmt, ok := stack[len(stack)-2].(*ast.ExprStmt); ok {
+			if _, chained := astutil.Unparen(sel.X).(*ast.CallExpr); !chained {
+				if !c.pure(sel.X) || !c.removable(call) {
+					return analysis.SuggestedFix{}, false
+				}
+				edit, ok := c.deleteLines(stmt)
+				fix.TextEdits = []analysis.TextEdit{edit}
+				return fix, ok
+			}
+		}
+	}
+	// the setter returns its receiver, which takes its place
+	if !types.Identical(c.pass.TypesInfo.TypeOf(sel.X), c.pass.TypesInfo.TypeOf(call)) || !c.removable(call.Args[0]) {
+		return analysis.SuggestedFix{}, false
+	}
+	fix.TextEdits = []analysis.TextEdit{{Pos: sel.X.End(), End: call.End()}}
+	return fix, true
+}
+
+// fieldFix deletes the element kv of the options literal lit, if its field is one of removableOptions.
+func (c *checker) fieldFix(lit *ast.CompositeLit, kv *ast.KeyValueExpr, field string) (analysis.SuggestedFix, bool) {
+	if !contains(removableOptions, field) || !c.pure(kv.Value) || !c.removable(kv.Value) {
+		return analysis.SuggestedFix{}, false
+	}
+	fix := analysis.SuggestedFix{Message: "Remove the field " + field}
+	for i, elt := range lit.Elts {
+		if elt != kv {
+			continue
+		}
+		switch {
+		case len(lit.Elts) == 1:
+			fix.TextEdits = []analysis.TextEdit{{Pos: lit.Lbrace + 1, End: lit.Rbrace}}
+		case i < len(lit.Elts)-1:
+			fix.TextEdits = []analysis.TextEdit{{Pos: kv.Pos(), End: lit.Elts[i+1].Pos()}}
+		default:
+			fix.TextEdits = []analysis.TextEdit{{Pos: lit.Elts[i-1].End(), End: kv.End()}}
+		}
+		return fix, true
+	}
+	return analysis.SuggestedFix{}, false
+}
+
+// pure reports whether exprs have no side effects, so that a fix may evaluate them in another order or
+// more than once: they call no functions other than conversions and context.Background or context.TODO,
+// and receive from no channel.
+func (c *checker) pure(exprs ...ast.Expr) bool {
+	pure := true
+	for _, expr := range exprs {
+		ast.Inspect(expr, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				if tv, ok := c.pass.TypesInfo.Types[n.Fun]; ok && tv.IsType() {
+					return true
+				}
+				if fn, ok := typeutil.Callee(c.pass.TypesInfo, n).(*types.Func); ok && fn.Pkg() != nil && fn.Pkg().Path() == "context" &&
+					(fn.Name() == "Background" || fn.Name() == "TODO") {
+					return true
+				}
+				pure = false
+			case *ast.UnaryExpr:
+				if n.Op == token.ARROW {
+					pure = false
+				}
+			case *ast.FuncLit:
+				return false
+			}
+			return pure
+		})
+	}
+	return pure
+}
+
+// removable reports whether deleting node leaves the local variables that it uses used elsewhere, as
+// Go rejects variables that are declared and not used.
+func (c *checker) removable(node ast.Node) bool {
+	used := map[*types.Var]bool{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if v, ok := c.pass.TypesInfo.Uses[ident].(*types.Var); ok && !v.IsField() && v.Parent() != nil && v.Parent() != v.Pkg().Scope() {
+				used[v] = true
+			}
+		}
+		return true
+	})
+	for ident, obj := range c.pass.TypesInfo.Uses {
+		if v, ok := obj.(*types.Var); ok && used[v] && (ident.Pos() < node.Pos() || node.End() <= ident.Pos()) {
+			delete(used, v)
+		}
+	}
+	return len(used) == 0
+}
+
+// deleteLines returns the edit that deletes the lines of node, a statement or a comment, which must hold nothing else.
+func (c *checker) deleteLines(node ast.Node) (analysis.TextEdit, bool) {
+	if c.codeBefore(node.Pos()) || c.codeAfter(node.End()) {
+		return analysis.TextEdit{}, false
+	}
+	file := c.pass.Fset.File(node.Pos())
+	start := file.LineStart(file.Line(node.Pos()))
+	end := token.Pos(file.Base() + file.Size())
+	if endLine := file.Line(node.End()); endLine < file.LineCount() {
+		end = file.LineStart(endLine + 1)
+	}
+	return analysis.TextEdit{Pos: start, End: end}, true
+}
+
+// codeBefore reports whether the line of pos holds a node or a comment that ends before pos. The
+// positions of the syntax tree tell, so the source does not need to be read.
+func (c *checker) codeBefore(pos token.Pos) bool {
+	tf := c.pass.Fset.File(pos)
+	line := tf.Line(pos)
+	return c.lineHolds(pos, func(from, to token.Pos) bool {
+		return to <= pos && tf.Line(to) == line
+	})
+}
+
+// codeAfter reports whether the line of pos holds a node or a comment that starts at pos or after it.
+func (c *checker) codeAfter(pos token.Pos) bool {
+	tf := c.pass.Fset.File(pos)
+	line := tf.Line(pos)
+	return c.lineHolds(pos, func(from, to token.Pos) bool {
+		return from >= pos && tf.Line(from) == line
+	})
+}
+
+// lineHolds reports whether a node or a comment of the file of pos, whose range is from to to, is on
+// the line of pos as on reports.
+func (c *checker) lineHolds(pos token.Pos, on func(from, to token.Pos) bool) bool {
+	tf := c.pass.Fset.File(pos)
+	line := tf.Line(pos)
+	lineStart, lineEnd := tf.LineStart(line), token.Pos(tf.Base()+tf.Size())
+	if line < tf.LineCount() {
+		lineEnd = tf.LineStart(line + 1)
+	}
+	for _, file := range c.pass.Files {
+		if file.FileStart > pos || pos > file.FileEnd {
+			continue
+		}
+		for _, group := range file.Comments {
+			for _, comment := range group.List {
+				if on(comment.Pos(), comment.End()) {
+					return true
+				}
+			}
+		}
+		found := false
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n.(type) {
+			case nil, *ast.CommentGroup, *ast.Comment:
+				return false
+			}
+			if found || n.End() < lineStart || n.Pos() >= lineEnd {
+				return false
+			}
+			if _, ok := n.(*ast.File); !ok && on(n.Pos(), n.End()) {
+				found = true
+			}
+			return !found
+		})
+		return found
+	}
+	return false
+}
+
+// nodeText returns the source of node, as gofmt prints it.
+func (c *checker) nodeText(node ast.Node) string {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, c.pass.Fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// usesNames reports whether expr refers to any of names.
+func usesNames(expr ast.Expr, names ...string) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && contains(names, ident.Name) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
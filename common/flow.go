@@ -0,0 +1,241 @@
+package common
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/cfg"
+)
+
+// flowGraph is the control flow graph of a function body, with the predecessors of its blocks.
+type flowGraph struct {
+	cfg   *cfg.CFG
+	preds map[*cfg.Block][]*cfg.Block
+}
+
+// flow returns the control flow graph of body, building it on first use. All calls are taken to
+// return, as a call that does not, e.g. log.Fatal, only ends paths on which nothing is passed on.
+func (a *docAnalyzer) flow(body *ast.BlockStmt) *flowGraph {
+	if g, ok := a.flows[body]; ok {
+		return g
+	}
+	g := &flowGraph{
+		cfg:   cfg.New(body, func(*ast.CallExpr) bool { return true }),
+		preds: map[*cfg.Block][]*cfg.Block{},
+	}
+	for _, block := range g.cfg.Blocks {
+		for _, succ := range block.Succs {
+			g.preds[succ] = append(g.preds[succ], block)
+		}
+	}
+	a.flows[body] = g
+	return g
+}
+
+// enclosingBody returns the innermost function body that contains node, or nil.
+func (a *docAnalyzer) enclosingBody(node ast.Node) *ast.BlockStmt {
+	var body *ast.BlockStmt
+	for _, b := range a.bodies {
+		if b.Pos() <= node.Pos() && node.Pos() < b.End() && (body == nil || b.Pos() > body.Pos()) {
+			body = b
+		}
+	}
+	return body
+}
+
+// reachingDefs returns the definitions of a variable that reach its use, walking the control flow
+// graph of the function back from the use, and the map updates of the variable, such as
+// m["$indexStats"] = bson.M{}, on the way, in source order. For a package variable, or a variable
+// that a function literal captures, all definitions reach the use and there are no updates.
+func (a *docAnalyzer) reachingDefs(obj *types.Var, use *ast.Ident) ([]varDef, []*ast.AssignStmt) {
+	defs := a.defs[obj]
+	body := a.enclosingBody(use)
+	if body == nil {
+		return defs, nil
+	}
+	byNode := map[ast.Node]varDef{}
+	for _, def := range defs {
+		if def.scope == body {
+			byNode[def.node] = def
+		}
+	}
+	if len(byNode) == 0 {
+		return defs, nil
+	}
+
+	g := a.flow(body)
+	var start *cfg.Block
+	end := 0
+	for _, block := range g.cfg.Blocks {
+		for i, node := range block.Nodes {
+			if node.Pos() <= use.Pos() && use.Pos() < node.End() {
+				start, end = block, i
+			}
+		}
+	}
+	if start == nil {
+		return defs, nil
+	}
+
+	var reached []varDef
+	reachedNodes := map[ast.Node]bool{}
+	var updates []*ast.AssignStmt
+	updateNodes := map[ast.Node]bool{}
+	visited := map[*cfg.Block]bool{}
+	var walk func(block *cfg.Block, end int)
+	walk = func(block *cfg.Block, end int) {
+		for i := end - 1; i >= 0; i-- {
+			node := block.Nodes[i]
+			if def, ok := byNode[node]; ok {
+				if !reachedNodes[node] {
+					reachedNodes[node] = true
+					reached = append(reached, def)
+				}
+				return
+			}
+			if assign, ok := node.(*ast.AssignStmt); ok && !updateNodes[assign] && a.updatesMap(assign, obj) {
+				updateNodes[assign] = true
+				updates = append(updates, assign)
+			}
+		}
+		for _, pred := range g.preds[block] {
+			if !visited[pred] {
+				visited[pred] = true
+				walk(pred, len(pred.Nodes))
+			}
+		}
+	}
+	walk(start, end)
+
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Pos() < updates[j].Pos() })
+	return reached, updates
+}
+
+// updatesMap reports whether assign sets an element of the map variable obj, e.g. m[key] = value.
+func (a *docAnalyzer) updatesMap(assign *ast.AssignStmt, obj *types.Var) bool {
+	if !isMapType(obj.Type()) {
+		return false
+	}
+	for _, lhs := range assign.Lhs {
+		if index, ok := astutil.Unparen(lhs).(*ast.IndexExpr); ok {
+			if ident, ok := astutil.Unparen(index.X).(*ast.Ident); ok && a.pass.TypesInfo.Uses[ident] == obj {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// updated returns the map literals among exprs with the elements set by updates added, and the maps
+// made with make as literals of these elements alone. The other expressions are returned as they are.
+func (a *docAnalyzer) updated(exprs []ast.Expr, updates []*ast.AssignStmt) []ast.Expr {
+	var elts []ast.Expr
+	for _, assign := range updates {
+		if len(assign.Lhs) != len(assign.Rhs) {
+			continue
+		}
+		for i, lhs := range assign.Lhs {
+			if index, ok := astutil.Unparen(lhs).(*ast.IndexExpr); ok {
+				elts = append(elts, &ast.KeyValueExpr{Key: index.Index, Colon: index.Rbrack, Value: assign.Rhs[i]})
+			}
+		}
+	}
+
+	var result []ast.Expr
+	for _, x := range exprs {
+		switch x := x.(type) {
+		case *ast.CompositeLit:
+			if isMapType(a.typeOf(x)) {
+				result = append(result, a.build(x.Lbrace, a.typeOf(x), x.Elts, elts))
+				continue
+			}
+		case *ast.CallExpr:
+			if a.isBuiltin(x, "make") && isMapType(a.pass.TypesInfo.TypeOf(x)) {
+				result = append(result, a.build(x.Lparen, a.pass.TypesInfo.TypeOf(x), nil, elts))
+				continue
+			}
+		}
+		result = append(result, x)
+	}
+	return result
+}
+
+// appended returns the literals that append(base, elems...) stands for, a document or an array: one
+// for each literal that base may stand for, with the appended elements, or a literal of the appended
+// elements alone when base is nil or the zero value. Bases that are not literals are left out, as
+// the elements that come first are not known, and so is a base that stands for nothing known, e.g.
+// a parameter.
+func (a *docAnalyzer) appended(call *ast.CallExpr, seen map[ast.Node]bool) []ast.Expr {
+	if len(call.Args) == 0 {
+		return nil
+	}
+	typ := a.pass.TypesInfo.TypeOf(call)
+
+	var elts []ast.Expr
+	if call.Ellipsis.IsValid() {
+		// append(base, other...)
+		if len(call.Args) == 2 {
+			for _, x := range a.resolve(call.Args[1], seen) {
+				if lit, ok := x.(*ast.CompositeLit); ok {
+					elts = append(elts, lit.Elts...)
+				}
+			}
+		}
+	} else {
+		elts = call.Args[1:]
+	}
+
+	bases := a.resolve(call.Args[0], seen)
+	if len(bases) == 0 {
+		if !a.isZero(call.Args[0]) {
+			return nil
+		}
+		return []ast.Expr{a.build(call.Lparen, typ, nil, elts)}
+	}
+	var lits []ast.Expr
+	for _, base := range bases {
+		if lit, ok := base.(*ast.CompositeLit); ok {
+			lits = append(lits, a.build(lit.Lbrace, typ, lit.Elts, elts))
+		}
+	}
+	return lits
+}
+
+// isZero reports whether expr, which resolves to no definition, is nil or a local variable that is
+// declared without a value, e.g. var cmd bson.D, and so holds the zero value.
+func (a *docAnalyzer) isZero(expr ast.Expr) bool {
+	ident, ok := astutil.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	switch obj := a.pass.TypesInfo.Uses[ident].(type) {
+	case *types.Nil:
+		return true
+	case *types.Var:
+		if obj.Parent() == nil || obj.Parent() == obj.Pkg().Scope() {
+			return false
+		}
+		for _, file := range a.pass.Files {
+			if file.Pos() <= obj.Pos() && obj.Pos() < file.End() {
+				path, _ := astutil.PathEnclosingInterval(file, obj.Pos(), obj.Pos())
+				for _, node := range path {
+					if spec, ok := node.(*ast.ValueSpec); ok {
+						return len(spec.Values) == 0
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// build returns a literal of type typ at pos with the elements of base followed by elts, which
+// stands for a variable built up in steps.
+func (a *docAnalyzer) build(pos token.Pos, typ types.Type, base, elts []ast.Expr) *ast.CompositeLit {
+	lit := &ast.CompositeLit{Lbrace: pos, Elts: append(append([]ast.Expr(nil), base...), elts...), Rbrace: pos}
+	a.built[lit] = typ
+	return lit
+}
@@ -0,0 +1,433 @@
+package common
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// The stability of a call of the inventory.
+const (
+	StabilityStable   = "stable"
+	StabilityUnstable = "unstable"
+	// The command run by RunCommand could not be determined.
+	StabilityUnresolved = "unresolved"
+)
+
+var inventoryFlag bool
+
+func init() {
+	StableAnalyzer.Flags.BoolVar(&inventoryFlag, "inventory-calls", false,
+		"list every call of a method of a client, database, collection or index view in the result of the analyzer instead of the findings, for gostable -inventory")
+}
+
+// inventoryTypes are the driver types whose method calls are listed by the inventory.
+var inventoryTypes = []string{"Client", "Database", "Collection", "IndexView", "SearchIndexView"}
+
+// InventoryEntry is what the inventory tells of a call of a method of an inventory type.
+type InventoryEntry struct {
+	// Driver is the module of the driver, e.g. go.mongodb.org/mongo-driver/v2.
+	Driver string `json:"driver"`
+	// Receiver is the type of the method, e.g. Collection, and Method its name.
+	Receiver string `json:"receiver"`
+	Method   string `json:"method"`
+	// Via describes how the method is called when it is not named by the call, as in the findings.
+	Via string `json:"via,omitempty"`
+	// Commands are the server commands that the call runs: those of the method, or those of the
+	// command documents passed to RunCommand.
+	Commands []string `json:"commands"`
+	// Database and Collection are the names of the database and the collection that the call runs
+	// on, "" if they are not constant or not known. For RunCommand, the collection is the value of
+	// the command, e.g. {"count": "orders"}.
+	Database   string `json:"database,omitempty"`
+	Collection string `json:"collection,omitempty"`
+	// Options are the options that the options arguments set, by their setters without Set, e.g.
+	// Limit, or the fields of an options literal. OptionsResolved is false if the construction of
+	// some options is not known, e.g. options passed in as a parameter.
+	Options         []string `json:"options"`
+	OptionsResolved bool     `json:"optionsResolved"`
+	Stability       string   `json:"stability"`
+}
+
+// InventoryCall is a call of a method of an inventory type, as the analyzer lists it with -inventory-calls.
+type InventoryCall struct {
+	// Pos is the position of the method name of the call, or of the call when it names no method.
+	Pos token.Pos
+	InventoryEntry
+}
+
+// takeInventory returns the calls of the methods of the inventory types, one for each method that a
+// call may call.
+func (c *checker) takeInventory(inspect *inspector.Inspector) []InventoryCall {
+	var calls []InventoryCall
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node) {
+		call := node.(*ast.CallExpr)
+		pos := call.Pos()
+		if sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+			pos = sel.Sel.Pos()
+		}
+		for _, method := range c.calledMethods(call) {
+			if entry, ok := c.inventoryEntry(call, method); ok {
+				calls = append(calls, InventoryCall{Pos: pos, InventoryEntry: entry})
+			}
+		}
+	})
+	return calls
+}
+
+// inventoryEntry returns the entry of a method that call calls, if it is a method of an inventory type.
+func (c *checker) inventoryEntry(call *ast.CallExpr, method methodCall) (InventoryEntry, bool) {
+	fn := method.fn
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil || !c.isHandleType(recv.Type()) {
+		return InventoryEntry{}, false
+	}
+	typeName := receiverTypeName(fn)
+	entry := InventoryEntry{
+		Driver:          c.cat.driverModule(fn.Pkg().Path()),
+		Receiver:        typeName,
+		Method:          fn.Name(),
+		Via:             method.via,
+		Commands:        append([]string{}, methodCommands[typeName][fn.Name()]...),
+		OptionsResolved: true,
+		Stability:       StabilityStable,
+	}
+	switch sig := fn.Type().(*types.Signature); {
+	case method.via != "":
+	case sig.Results().Len() == 1 && c.isHandleType(sig.Results().At(0).Type()):
+		// a method that derives a handle, e.g. client.Database("shop"), which runs no command
+		entry.Database, entry.Collection = c.derivedNames(call, map[ast.Node]bool{})
+	default:
+		entry.Database, entry.Collection = c.handleNames(c.callReceiver(call), map[ast.Node]bool{})
+	}
+	if _, ok := c.symbols().rule(fn); ok {
+		entry.Stability = StabilityUnstable
+	}
+
+	if c.symbols().commands[fn] {
+		names, collection, unstable, resolved := c.runCommandInventory(method.args)
+		entry.Commands, entry.Collection = append(entry.Commands, names...), collection
+		switch {
+		case unstable:
+			entry.Stability = StabilityUnstable
+		case !resolved:
+			entry.Stability = StabilityUnresolved
+		}
+	}
+	if index, ok := c.symbols().pipelines[fn]; ok && index < len(method.args) {
+		if c.unstablePipeline(method.args[index]) {
+			entry.Stability = StabilityUnstable
+		}
+	}
+
+	set := map[string]bool{}
+	for i, arg := range method.args {
+		typ := c.pass.TypesInfo.TypeOf(arg)
+		if slice, ok := typ.(*types.Slice); ok && call.Ellipsis.IsValid() && i == len(method.args)-1 {
+			// options passed as a slice, e.g. coll.Find(ctx, filter, opts...)
+			if optionsType(c.cat, slice.Elem()) != nil {
+				entry.OptionsResolved = false
+			}
+			continue
+		}
+		named := optionsType(c.cat, typ)
+		if named == nil {
+			continue
+		}
+		options, unstable, ok := c.optionsInventory(arg, named)
+		if unstable {
+			entry.Stability = StabilityUnstable
+		}
+		if !ok {
+			entry.OptionsResolved = false
+		}
+		for _, option := range options {
+			set[option] = true
+		}
+	}
+	entry.Options = []string{}
+	for option := range set {
+		entry.Options = append(entry.Options, option)
+	}
+	sort.Strings(entry.Options)
+	return entry, true
+}
+
+// optionsType returns the named type of the options of the driver that typ is, or points to, or nil.
+func optionsType(cat *Catalog, typ types.Type) *types.Named {
+	if typ == nil {
+		return nil
+	}
+	if ptr, ok := types.Unalias(typ).(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok || !isDriverType(cat, named, optsPkgName, named.Obj().Name()) {
+		return nil
+	}
+	return named
+}
+
+// optionsInventory returns the options that expr, options of type named, sets, and whether one of them
+// is not supported, including those of the options returned by the functions of other packages. It
+// is false if the construction of the options is not known.
+func (c *checker) optionsInventory(expr ast.Expr, named *types.Named) ([]string, bool, bool) {
+	if tv, ok := c.pass.TypesInfo.Types[astutil.Unparen(expr)]; ok && tv.IsNil() {
+		return nil, false, true
+	}
+	if u, ok := astutil.Unparen(expr).(*ast.UnaryExpr); ok && u.Op == token.AND {
+		// the address of an options variable, e.g. &opts
+		expr = u.X
+	}
+	unstable := false
+	for _, producer := range c.resultProducers(expr) {
+		for _, finding := range c.resultOf(producer).Findings {
+			unstable = unstable || finding.Kind == optionFinding
+		}
+	}
+
+	values, ok := c.optionValues(expr, named.Obj().Name())
+	var options []string
+	for _, v := range values {
+		for setter, args := range v.set {
+			name := setter[len("Set"):]
+			options = append(options, name)
+			if fn := lookupMethod(named.Obj().Pkg(), named.Obj().Name(), setter); fn != nil {
+				if _, bad := c.symbols().rule(fn); bad {
+					unstable = true
+				}
+			}
+			if field := lookupField(named.Obj().Pkg(), named.Obj().Name(), name); field != nil {
+				if _, bad := c.symbols().rule(field); bad {
+					unstable = true
+				}
+			}
+			// an unsupported option constant, e.g. options.Tailable
+			for _, arg := range args {
+				if sel, isSel := astutil.Unparen(arg).(*ast.SelectorExpr); isSel {
+					if _, bad := c.symbols().rule(c.pass.TypesInfo.Uses[sel.Sel]); bad {
+						unstable = true
+					}
+				}
+			}
+		}
+	}
+	return options, unstable, ok
+}
+
+// unstablePipeline reports whether the pipeline expr has a stage that is not supported, including the
+// pipelines returned by the functions of other packages.
+func (c *checker) unstablePipeline(expr ast.Expr) bool {
+	for _, stage := range c.pipelineStages(expr, map[ast.Node]bool{}) {
+		for _, name := range stage.names {
+			if contains(c.cat.Stages, name) {
+				return true
+			}
+		}
+	}
+	for _, producer := range c.resultProducers(expr) {
+		for _, finding := range c.resultOf(producer).Findings {
+			if finding.Kind == stageFinding {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runCommandInventory returns the commands of the command documents passed to RunCommand or
+// RunCommandCursor, as analyzeRunCommand finds them, the collection that the commands name when
+// their value is a constant, and whether a command, a field of a command or a stage of its pipeline
+// is not supported. It is false if a command could not be determined.
+func (c *checker) runCommandInventory(args []ast.Expr) ([]string, string, bool, bool) {
+	if len(args) < 2 {
+		return nil, "", false, false
+	}
+	candidates := c.docs.resolveMarshaled(args[1], map[ast.Node]bool{})
+	if len(candidates) == 0 {
+		candidates = []ast.Expr{args[1]}
+	}
+	var commands, collections []string
+	unstable, resolved := false, true
+	for _, x := range candidates {
+		var names []string
+		switch x := x.(type) {
+		case *ast.CompositeLit:
+			if key, ok := c.commandKey(x); ok {
+				names = key.names
+				if key.value != nil {
+					if values := c.docs.stringValues(key.value); len(values) == 1 {
+						collections = append(collections, values[0])
+					}
+				}
+				for _, name := range names {
+					unstable = unstable || c.unstableCommandDoc(x, name)
+				}
+			}
+		case *ast.CallExpr:
+			if producer := c.resultProducer(x); producer != nil {
+				for _, cmd := range c.resultOf(producer).Commands {
+					names = append(names, cmd.Name)
+				}
+			}
+		}
+		if len(names) == 0 {
+			if keys := c.docs.typeKeys(c.docs.typeOf(x), x); len(keys) > 0 {
+				names = keys[0].names
+			}
+		}
+		if len(names) == 0 {
+			resolved = false
+		}
+		for _, name := range names {
+			if _, bad := c.commandMessage(name); bad {
+				unstable = true
+			}
+			if !contains(commands, name) {
+				commands = append(commands, name)
+			}
+		}
+	}
+	collection := ""
+	if len(collections) == len(candidates) && len(collections) > 0 {
+		collection = collections[0]
+		for _, name := range collections[1:] {
+			if name != collection {
+				collection = ""
+			}
+		}
+	}
+	return commands, collection, unstable, resolved
+}
+
+// unstableCommandDoc reports whether the command document doc of the supported command name carries a
+// field that the command may not, or an unsupported stage in its pipeline, as checkCommandFields does.
+func (c *checker) unstableCommandDoc(doc *ast.CompositeLit, name string) bool {
+	cmd, ok := c.cat.command(name)
+	if !ok {
+		return false
+	}
+	if len(c.limitedFields(doc, cmd.Fields)) > 0 {
+		return true
+	}
+	for _, key := range c.docs.literalKeys(doc)[1:] {
+		for _, field := range key.names {
+			if field == cmd.Pipeline && key.value != nil && c.unstablePipeline(key.value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleNames returns the names of the database and the collection that expr, a client, database,
+// collection or index view, stands for, "" for those that are not constant or not known. They are
+// traced as connects traces the clients: through variables, the parameters of the functions of the
+// package, and the methods that derive a handle from another, e.g. client.Database("shop").
+func (c *checker) handleNames(expr ast.Expr, seen map[ast.Node]bool) (string, string) {
+	if expr == nil {
+		return "", ""
+	}
+	var candidates []ast.Expr
+	if ident, ok := astutil.Unparen(expr).(*ast.Ident); ok {
+		if obj, ok := c.pass.TypesInfo.Uses[ident].(*types.Var); ok {
+			if decl, index := c.paramOf(obj); decl != nil {
+				if seen[decl] {
+					return "", ""
+				}
+				seen[decl] = true
+				defer delete(seen, decl)
+				args, ok := c.paramArgs(decl, index)
+				if !ok {
+					return "", ""
+				}
+				candidates = args
+			}
+		}
+	}
+	if candidates == nil {
+		candidates = c.docs.resolve(expr, seen)
+	}
+	if len(candidates) == 0 {
+		return "", ""
+	}
+
+	database, collection := c.derivedNames(candidates[0], seen)
+	for _, x := range candidates[1:] {
+		db, coll := c.derivedNames(x, seen)
+		if db != database {
+			database = ""
+		}
+		if coll != collection {
+			collection = ""
+		}
+	}
+	return database, collection
+}
+
+// derivedNames returns the names of the database and the collection of a handle that x, a call of the
+// driver, derives.
+func (c *checker) derivedNames(x ast.Expr, seen map[ast.Node]bool) (string, string) {
+	if ident, ok := astutil.Unparen(x).(*ast.Ident); ok {
+		// a parameter of the function, which resolves to itself
+		return c.handleNames(ident, seen)
+	}
+	call, ok := astutil.Unparen(x).(*ast.CallExpr)
+	if !ok {
+		return "", ""
+	}
+	fn, ok := typeutil.Callee(c.pass.TypesInfo, call).(*types.Func)
+	if !ok {
+		return "", ""
+	}
+	sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr)
+	recv := fn.Type().(*types.Signature).Recv()
+	if !ok || recv == nil || !c.isHandleType(recv.Type()) {
+		return "", ""
+	}
+	typeName := receiverTypeName(fn)
+	name := func() string {
+		if len(call.Args) == 0 {
+			return ""
+		}
+		if values := c.docs.stringValues(call.Args[0]); len(values) == 1 {
+			return values[0]
+		}
+		return ""
+	}
+
+	switch {
+	case typeName == "Client" && fn.Name() == "Database":
+		return name(), ""
+	case typeName == "Database" && fn.Name() == "Collection":
+		database, _ := c.handleNames(sel.X, seen)
+		return database, name()
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Results().Len() == 0 {
+		return "", ""
+	}
+	database, collection := c.handleNames(sel.X, seen)
+	switch result := sig.Results().At(0).Type(); {
+	case isDriverType(c.cat, result, mongoPkgName, "Client"):
+		return "", ""
+	case isDriverType(c.cat, result, mongoPkgName, "Database"):
+		return database, ""
+	}
+	return database, collection
+}
+
+// isHandleType reports whether typ is one of the inventory types of the driver.
+func (c *checker) isHandleType(typ types.Type) bool {
+	for _, name := range inventoryTypes {
+		if isDriverType(c.cat, typ, mongoPkgName, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package common
+
+import (
+	"go/ast"
+
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// pipelineMethods maps the driver methods that run an aggregation pipeline to the index of their pipeline argument.
+var pipelineMethods = map[string]map[string]int{
+	"Collection": {"Aggregate": 1},
+	"Database":   {"Aggregate": 1, "CreateView": 3},
+}
+
+// subPipelineStages are the stages whose value holds pipelines of its own:
+// each value of a $facet document, and the "pipeline" of $lookup and $unionWith.
+var subPipelineStages = map[string]string{
+	"$facet":     "",
+	"$lookup":    "pipeline",
+	"$unionWith": "pipeline",
+}
+
+// checkPipelines flags restricted stages in the pipelines passed to the driver, and in any mongo.Pipeline.
+func (c *checker) checkPipelines(inspect *inspector.Inspector) {
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node) {
+		call := node.(*ast.CallExpr)
+		for _, method := range c.calledMethods(call) {
+			fn := method.fn
+			argIndex, ok := c.symbols().pipelines[fn]
+			if !ok || len(method.args) <= argIndex {
+				continue
+			}
+			recv := receiverTypeName(fn)
+
+			for _, stage := range c.pipelineStages(method.args[argIndex], map[ast.Node]bool{}) {
+				for _, name := range stage.names {
+					if contains(c.cat.Stages, name) {
+						c.report(ruleUnstableStage, c.cat.stageDetails(name), c.receiverClients(c.callReceiver(call)), stage.expr.Pos(), "Aggregation stage '%s' passed to %s.%s is not supported by the MongoDB Stable API",
+							name, recv, fn.Name())
+						c.reportedStages[stage.expr.Pos()] = true
+					}
+				}
+			}
+		}
+	})
+
+	// Pipelines that are not passed to the driver directly, e.g. returned by a function
+	inspect.Preorder([]ast.Node{(*ast.CompositeLit)(nil)}, func(node ast.Node) {
+		lit := node.(*ast.CompositeLit)
+		if !isDriverType(c.cat, c.pass.TypesInfo.TypeOf(lit), mongoPkgName, "Pipeline") {
+			return
+		}
+
+		for _, stage := range c.pipelineStages(lit, map[ast.Node]bool{}) {
+			if c.reportedStages[stage.expr.Pos()] {
+				continue
+			}
+			for _, name := range stage.names {
+				if contains(c.cat.Stages, name) {
+					c.report(ruleUnstableStage, c.cat.stageDetails(name), usageClients{}, stage.expr.Pos(), "Aggregation stage '%s' in mongo.Pipeline is not supported by the MongoDB Stable API", name)
+				}
+			}
+		}
+	})
+}
+
+// pipelineStages returns the stage keys of the pipelines that expr may stand for, including the
+// stages of nested pipelines. A pipeline is an array of stage documents; a single document is
+// taken as a pipeline of one stage.
+func (c *checker) pipelineStages(expr ast.Expr, seen map[ast.Node]bool) []docKey {
+	var stageDocs []ast.Expr
+	for _, x := range c.docs.resolve(expr, seen) {
+		if c.docs.isDocumentType(c.docs.typeOf(x)) {
+			stageDocs = append(stageDocs, x)
+		} else {
+			stageDocs = append(stageDocs, c.docs.elements(x, seen)...)
+		}
+	}
+
+	var stages []docKey
+	for _, doc := range stageDocs {
+		for _, stage := range c.docs.documentKeys(doc, seen) {
+			stages = append(stages, stage)
+
+			for _, name := range stage.names {
+				field, ok := subPipelineStages[name]
+				if !ok || stage.value == nil {
+					continue
+				}
+				for _, key := range c.docs.documentKeys(stage.value, seen) {
+					if field == "" || contains(key.names, field) {
+						stages = append(stages, c.pipelineStages(key.value, seen)...)
+					}
+				}
+			}
+		}
+	}
+	return stages
+}
@@ -0,0 +1,106 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rule IDs. Each kind of finding has its own rule, whose ID is set as the category of its diagnostics.
+// IDs are never reused for another rule.
+const (
+	// An unsupported driver method is called.
+	ruleUnstableMethod = "GS001-unstable-method"
+	// An unsupported options field is set or read, or an unsupported option constant is used.
+	ruleUnstableField = "GS002-unstable-field"
+	// An unsupported aggregation stage is in a pipeline.
+	ruleUnstableStage = "GS003-unstable-stage"
+	// An unsupported command is passed to RunCommand.
+	ruleUnstableCommand = "GS004-unstable-command"
+	// The command passed to RunCommand could not be determined and has to be reviewed.
+	ruleUnresolvedCommand = "GS005-unresolved-command"
+	// A command that is supported with limitations carries a field that is not supported.
+	ruleUnstableCommandField = "GS006-unstable-command-field"
+	// The command document is a map with several keys, whose order, and so the command, is undefined.
+	ruleUnorderedCommand = "GS007-unordered-command"
+	// A client is made without requesting the strict Stable API V1 with deprecation errors.
+	ruleClientConfig = "GS008-client-config"
+	// A //gostable:ignore directive has no reason, names an unknown rule, or suppresses no finding.
+	ruleSuppression = "GS009-suppression"
+)
+
+// ruleIDs are the IDs of the rules, which suppression directives name.
+var ruleIDs = []string{ruleUnstableMethod, ruleUnstableField, ruleUnstableStage, ruleUnstableCommand, ruleUnresolvedCommand,
+	ruleUnstableCommandField, ruleUnorderedCommand, ruleClientConfig, ruleSuppression}
+
+// RuleDescriptor describes a rule for reports, e.g. the rule descriptors of a SARIF log.
+type RuleDescriptor struct {
+	ID string
+	// Name is the ID without its number, e.g. unstable-method.
+	Name             string
+	ShortDescription string
+	// FullDescription lists the entries of the catalog that the rule checks, if it is driven by the catalog.
+	FullDescription string
+	HelpURI         string
+	// DefaultLevel is the level of the findings whose severity is not known from their client.
+	DefaultLevel string
+}
+
+// Rules returns the descriptors of all rules, in ID order, described from the active catalog.
+func Rules() ([]RuleDescriptor, error) {
+	cat, err := activeCatalog()
+	if err != nil {
+		return nil, err
+	}
+
+	var stableCommands, limitedCommands []string
+	for _, cmd := range cat.Commands {
+		if len(cmd.Since) > 0 && !cmd.Since.includes(cat.serverVersion) {
+			continue
+		}
+		stableCommands = append(stableCommands, cmd.Name)
+		if len(cmd.Fields) > 0 {
+			limitedCommands = append(limitedCommands, fmt.Sprintf("%s (%s)", cmd.Name, strings.Join(cmd.Fields, ", ")))
+		}
+	}
+
+	rules := []RuleDescriptor{
+		{ID: ruleUnstableMethod, ShortDescription: "Driver method outside the MongoDB Stable API",
+			FullDescription: "Unsupported methods: " + strings.Join(cat.symbolNames(cat.methodRules()), ", ") + ".", HelpURI: "#function-calls"},
+		{ID: ruleUnstableField, ShortDescription: "Options field or option constant outside the MongoDB Stable API",
+			FullDescription: "Unsupported fields and constants: " + strings.Join(cat.symbolNames(cat.fieldRules()), ", ") + ".", HelpURI: "#structs"},
+		{ID: ruleUnstableStage, ShortDescription: "Aggregation stage outside the MongoDB Stable API",
+			FullDescription: "Unsupported stages: " + strings.Join(cat.Stages, ", ") + ".", HelpURI: "#aggregation-stages"},
+		{ID: ruleUnstableCommand, ShortDescription: "Command outside the MongoDB Stable API run with RunCommand",
+			FullDescription: "Supported commands: " + strings.Join(stableCommands, ", ") + ".", HelpURI: "#runcommand"},
+		{ID: ruleUnresolvedCommand, ShortDescription: "Command run with RunCommand that could not be determined", HelpURI: "#runcommand"},
+		{ID: ruleUnstableCommandField, ShortDescription: "Command field outside the MongoDB Stable API",
+			FullDescription: "Commands supported with limitations, and their unsupported fields: " + strings.Join(limitedCommands, "; ") + ".", HelpURI: "#runcommand"},
+		{ID: ruleUnorderedCommand, ShortDescription: "Command document whose keys have no defined order", HelpURI: "#runcommand"},
+		{ID: ruleClientConfig, ShortDescription: "Client that does not request the strict Stable API V1 with deprecation errors", HelpURI: "#client-configuration"},
+		{ID: ruleSuppression, ShortDescription: "Suppression directive without a reason, for an unknown rule, or that suppresses no finding", HelpURI: "#suppressions"},
+	}
+	for i := range rules {
+		_, rules[i].Name, _ = strings.Cut(rules[i].ID, "-")
+		rules[i].HelpURI = "https://github.com/fsnow/gostable#" + strings.TrimPrefix(rules[i].HelpURI, "#")
+		rules[i].DefaultLevel = "warning"
+	}
+	return rules, nil
+}
+
+// symbolNames returns the names of the members of rules that are not supported on the server version,
+// e.g. mongo.Collection.Distinct, once each and sorted.
+func (c *Catalog) symbolNames(rules []SymbolRule) []string {
+	var names []string
+	for _, rule := range rules {
+		if !rule.unsupportedOn(c.serverVersion) {
+			continue
+		}
+		pkg := rule.Package[strings.LastIndex(rule.Package, "/")+1:]
+		for _, name := range rule.Names {
+			names = appendMissing(names, pkg+"."+rule.Type+"."+name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,355 @@
+package common
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// severity is how the server treats unsupported usage, which depends on the client that runs it.
+// The severities are ordered, so that usage run by several clients has the lowest of theirs.
+type severity int
+
+const (
+	// The client is not known, e.g. a collection passed in from another package.
+	severityUnknown severity = iota
+	// The client does not request the strict Stable API, so the server accepts the usage.
+	severityWarning
+	// The client requests the strict Stable API, so the server rejects the usage.
+	severityError
+)
+
+func (s severity) String() string {
+	switch s {
+	case severityError:
+		return "error"
+	case severityWarning:
+		return "warning"
+	}
+	return "unknown"
+}
+
+// usageClients holds the severity of usage and the calls that make the clients that may run it.
+type usageClients struct {
+	severity severity
+	connects []*ast.CallExpr
+}
+
+// receiverClients returns the severity of the usage run by a driver call on recv, a collection,
+// database or client, and the calls that make its clients. The severity is unknown when recv is nil,
+// e.g. for a method value.
+func (c *checker) receiverClients(recv ast.Expr) usageClients {
+	if recv == nil {
+		return usageClients{}
+	}
+	return c.clientsOf([]ast.Expr{recv})
+}
+
+// optionClients returns the severity of unsupported options that node, a setter call, an options
+// literal or a selector of an options field, sets or reads, from the driver calls of the function that
+// the options are passed to: the options written in the call, the options that a variable passed to the
+// call holds, and the options that the setters called on that variable set. The severity is unknown
+// when the options are not passed to the driver in the function.
+func (c *checker) optionClients(node ast.Expr) usageClients {
+	body := c.docs.enclosingBody(node)
+	if body == nil {
+		return usageClients{}
+	}
+	// the variable that holds the options, e.g. opts in opts.SetMax(10) or opts.Max = &max
+	var root types.Object
+	for x := astutil.Unparen(node); x != nil; {
+		switch e := x.(type) {
+		case *ast.CallExpr:
+			x = astutil.Unparen(e.Fun)
+			continue
+		case *ast.SelectorExpr:
+			x = astutil.Unparen(e.X)
+			continue
+		case *ast.Ident:
+			if obj, ok := c.pass.TypesInfo.Uses[e].(*types.Var); ok {
+				root = obj
+			}
+		}
+		break
+	}
+	contains := func(x ast.Node) bool { return x.Pos() <= node.Pos() && node.Pos() < x.End() }
+
+	var recvs []ast.Expr
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		recv := c.callReceiver(call)
+		if recv == nil || !c.isClientType(c.pass.TypesInfo.TypeOf(recv)) {
+			return true
+		}
+		for _, arg := range call.Args {
+			passed := contains(arg)
+			if u, ok := astutil.Unparen(arg).(*ast.UnaryExpr); ok && u.Op == token.AND {
+				arg = u.X
+			}
+			if ident, ok := astutil.Unparen(arg).(*ast.Ident); ok && root != nil && c.pass.TypesInfo.Uses[ident] == root {
+				passed = true
+			}
+			for _, x := range c.docs.resolve(arg, map[ast.Node]bool{}) {
+				passed = passed || contains(x)
+			}
+			if passed {
+				recvs = append(recvs, recv)
+				break
+			}
+		}
+		return true
+	})
+	if len(recvs) == 0 {
+		return usageClients{}
+	}
+	return c.clientsOf(recvs)
+}
+
+// clientsOf returns the severity of usage run on all of recvs, and the calls that make their clients.
+func (c *checker) clientsOf(recvs []ast.Expr) usageClients {
+	var connects []*ast.CallExpr
+	for _, recv := range recvs {
+		calls, ok := c.connects(recv, map[ast.Node]bool{})
+		if !ok || len(calls) == 0 {
+			return usageClients{}
+		}
+		connects = append(connects, calls...)
+	}
+	sort.Slice(connects, func(i, j int) bool { return connects[i].Pos() < connects[j].Pos() })
+	connects = slices.Compact(connects)
+
+	sev := severityError
+	for _, call := range connects {
+		fn := typeutil.Callee(c.pass.TypesInfo, call).(*types.Func)
+		sev = min(sev, c.connectSeverity(call, fn))
+	}
+	return usageClients{severity: sev, connects: connects}
+}
+
+// connects returns the calls of mongo.Connect or mongo.NewClient that make the client of the collection,
+// database or client that expr may stand for, through variables, the parameters of the functions of
+// the package, and the driver methods that derive one from another, such as client.Database(name). It
+// is false if some of them are not known.
+func (c *checker) connects(expr ast.Expr, seen map[ast.Node]bool) ([]*ast.CallExpr, bool) {
+	if ident, ok := astutil.Unparen(expr).(*ast.Ident); ok {
+		if obj, ok := c.pass.TypesInfo.Uses[ident].(*types.Var); ok {
+			if decl, index := c.paramOf(obj); decl != nil {
+				return c.argConnects(decl, index, seen)
+			}
+		}
+	}
+
+	candidates := c.docs.resolve(expr, seen)
+	if len(candidates) == 0 {
+		return nil, false
+	}
+	var calls []*ast.CallExpr
+	for _, x := range candidates {
+		call, ok := x.(*ast.CallExpr)
+		if !ok {
+			return nil, false
+		}
+		fn, ok := typeutil.Callee(c.pass.TypesInfo, call).(*types.Func)
+		if !ok {
+			return nil, false
+		}
+		if c.symbols().connects[fn] {
+			calls = append(calls, call)
+			continue
+		}
+		// a collection, database or client derived from another one, e.g. db.Collection(name)
+		sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr)
+		if !ok || !c.isClientMethod(fn) {
+			return nil, false
+		}
+		more, ok := c.connects(sel.X, seen)
+		if !ok {
+			return nil, false
+		}
+		calls = append(calls, more...)
+	}
+	return calls, true
+}
+
+// isClientMethod reports whether fn is a method of a collection, database or client of the driver
+// that returns one of them.
+func (c *checker) isClientMethod(fn *types.Func) bool {
+	sig := fn.Type().(*types.Signature)
+	return sig.Recv() != nil && c.isClientType(sig.Recv().Type()) && sig.Results().Len() == 1 && c.isClientType(sig.Results().At(0).Type())
+}
+
+// isClientType reports whether typ is a collection, database or client of the driver.
+func (c *checker) isClientType(typ types.Type) bool {
+	for _, name := range []string{"Client", "Database", "Collection"} {
+		if isDriverType(c.cat, typ, mongoPkgName, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// paramOf returns the declaration of the function of the package that obj is a parameter of, and the
+// index of the parameter, or nil. Variadic parameters are left out.
+func (c *checker) paramOf(obj *types.Var) (*ast.FuncDecl, int) {
+	if obj.IsField() {
+		return nil, 0
+	}
+	for _, file := range c.pass.Files {
+		if obj.Pos() < file.Pos() || file.End() <= obj.Pos() {
+			continue
+		}
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.FuncDecl)
+			if !ok || obj.Pos() < decl.Type.Pos() || decl.Type.End() <= obj.Pos() {
+				continue
+			}
+			index := 0
+			for _, field := range decl.Type.Params.List {
+				for _, name := range field.Names {
+					if c.pass.TypesInfo.Defs[name] == obj {
+						if _, ok := field.Type.(*ast.Ellipsis); ok {
+							return nil, 0
+						}
+						return decl, index
+					}
+					index++
+				}
+			}
+		}
+	}
+	return nil, 0
+}
+
+// argConnects returns the connects of the arguments passed as parameter index of decl by the calls of
+// the package. It is false if the arguments are not known, as for paramArgs.
+func (c *checker) argConnects(decl *ast.FuncDecl, index int, seen map[ast.Node]bool) ([]*ast.CallExpr, bool) {
+	if seen[decl] {
+		return nil, false
+	}
+	seen[decl] = true
+	defer delete(seen, decl)
+
+	args, ok := c.paramArgs(decl, index)
+	if !ok {
+		return nil, false
+	}
+	var calls []*ast.CallExpr
+	for _, arg := range args {
+		more, ok := c.connects(arg, seen)
+		if !ok {
+			return nil, false
+		}
+		calls = append(calls, more...)
+	}
+	return calls, true
+}
+
+// paramArgs returns the arguments passed as parameter index of decl by the calls of the package. It
+// is false if the function is used in other ways, e.g. as a function value, or may be called from
+// another package.
+func (c *checker) paramArgs(decl *ast.FuncDecl, index int) ([]ast.Expr, bool) {
+	fn, ok := c.pass.TypesInfo.Defs[decl.Name].(*types.Func)
+	if !ok || fn.Exported() {
+		return nil, false
+	}
+
+	var sites []*ast.CallExpr
+	called := map[*ast.Ident]bool{}
+	var uses []*ast.Ident
+	for _, file := range c.pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				if typeutil.StaticCallee(c.pass.TypesInfo, n) == fn {
+					sites = append(sites, n)
+					switch fun := astutil.Unparen(n.Fun).(type) {
+					case *ast.Ident:
+						called[fun] = true
+					case *ast.SelectorExpr:
+						called[fun.Sel] = true
+					}
+				}
+			case *ast.Ident:
+				if c.pass.TypesInfo.Uses[n] == fn {
+					uses = append(uses, n)
+				}
+			}
+			return true
+		})
+	}
+	for _, use := range uses {
+		if !called[use] {
+			return nil, false
+		}
+	}
+	if len(sites) == 0 {
+		return nil, false
+	}
+
+	var args []ast.Expr
+	for _, site := range sites {
+		if index >= len(site.Args) || site.Ellipsis.IsValid() {
+			return nil, false
+		}
+		args = append(args, site.Args[index])
+	}
+	return args, true
+}
+
+// severityNote returns the note that ends the message of usage, with its severity and the clients that run it.
+func (c *checker) severityNote(clients usageClients) string {
+	if clients.severity == severityUnknown {
+		return " [unknown: the client that runs it is not known]"
+	}
+	var origins []string
+	for _, call := range clients.connects {
+		origins = append(origins, c.origin(call.Pos()))
+	}
+	runBy := "the client made at " + origins[0]
+	if len(origins) > 1 {
+		runBy = "the clients made at " + strings.Join(origins, ", ")
+	}
+
+	switch {
+	case clients.severity == severityError && len(origins) == 1:
+		return fmt.Sprintf(" [error: run by %s, which requests the strict Stable API]", runBy)
+	case clients.severity == severityError:
+		return fmt.Sprintf(" [error: run by %s, which request the strict Stable API]", runBy)
+	case clients.severity == severityWarning && len(origins) == 1:
+		return fmt.Sprintf(" [warning: run by %s, which does not request the strict Stable API]", runBy)
+	}
+	return fmt.Sprintf(" [warning: run by %s, not all of which request the strict Stable API]", runBy)
+}
+
+// related returns the related information that points at the calls that make the clients of usage.
+func related(clients usageClients) []analysis.RelatedInformation {
+	var infos []analysis.RelatedInformation
+	for _, call := range clients.connects {
+		infos = append(infos, analysis.RelatedInformation{Pos: call.Pos(), End: call.End(), Message: "the client is made here"})
+	}
+	return infos
+}
+
+// callReceiver returns the receiver of a method call, e.g. coll in coll.Distinct(ctx, field, filter),
+// or nil if call does not name a method.
+func (c *checker) callReceiver(call *ast.CallExpr) ast.Expr {
+	sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	if selection, ok := c.pass.TypesInfo.Selections[sel]; !ok || selection.Kind() != types.MethodVal {
+		return nil
+	}
+	return sel.X
+}
@@ -0,0 +1,193 @@
+package common
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// bsonField is a field of a struct as the driver encodes it.
+type bsonField struct {
+	key       string
+	omitEmpty bool
+	// index is the path to the field from the struct, through the structs inlined on the way.
+	index []int
+}
+
+// bsonFields returns the fields of a struct type in the order the driver encodes them, with the fields
+// of inlined structs in place. The key of a field is the name in its bson tag, or its lowercased name.
+// Fields that are not encoded, unexported or tagged "-", are left out, and so are inlined maps, whose
+// keys are only known at run time.
+func bsonFields(st *types.Struct) []bsonField {
+	return appendBsonFields(nil, st, nil, map[*types.Struct]bool{})
+}
+
+func appendBsonFields(fields []bsonField, st *types.Struct, index []int, seen map[*types.Struct]bool) []bsonField {
+	if seen[st] {
+		return fields
+	}
+	seen[st] = true
+	defer delete(seen, st)
+
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if !field.Exported() {
+			continue
+		}
+		name, opts := parseBsonTag(st.Tag(i))
+		if name == "-" && len(opts) == 0 {
+			continue
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		if contains(opts, "inline") {
+			typ := field.Type()
+			if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+				typ = ptr.Elem()
+			}
+			if inlined, ok := typ.Underlying().(*types.Struct); ok {
+				fields = appendBsonFields(fields, inlined, fieldIndex, seen)
+			}
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name())
+		}
+		fields = append(fields, bsonField{key: name, omitEmpty: contains(opts, "omitempty"), index: fieldIndex})
+	}
+	return fields
+}
+
+// parseBsonTag returns the key and the options of a bson struct tag, e.g. `bson:"name,omitempty"`.
+// As with the driver's default parser, a tag that is not in the key:"value" form is a bson tag as a whole.
+func parseBsonTag(tag string) (string, []string) {
+	value, ok := reflect.StructTag(tag).Lookup("bson")
+	if !ok && !strings.Contains(tag, ":") {
+		value = tag
+	}
+	parts := strings.Split(value, ",")
+	return parts[0], parts[1:]
+}
+
+// isStructDocument reports whether typ is a struct that the driver encodes as a document, as opposed
+// to the structs of the driver itself, such as bson.E.
+func (a *docAnalyzer) isStructDocument(typ types.Type) bool {
+	if _, ok := typ.Underlying().(*types.Struct); !ok {
+		return false
+	}
+	if named, ok := types.Unalias(typ).(*types.Named); ok && named.Obj().Pkg() != nil && a.cat.driverModule(named.Obj().Pkg().Path()) != "" {
+		return false
+	}
+	return true
+}
+
+// structKeys returns the keys of a struct literal that the driver encodes, in order. Fields that are
+// left out when empty only have a key when the literal sets them to a value that may not be empty.
+func (a *docAnalyzer) structKeys(lit *ast.CompositeLit) []docKey {
+	st, ok := a.typeOf(lit).Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+
+	var keys []docKey
+	for _, field := range bsonFields(st) {
+		elt, value := a.fieldValue(lit, field.index)
+		if value == nil && field.omitEmpty || value != nil && field.omitEmpty && a.isEmptyValue(value) {
+			continue
+		}
+		key := docKey{names: []string{field.key}, elt: elt, expr: elt, value: value}
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			key.expr = kv.Key
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// typeKeys returns the keys of a value of a struct type that are encoded whatever the value, which are
+// the fields up to the first one that is left out when empty.
+func (a *docAnalyzer) typeKeys(typ types.Type, pos ast.Expr) []docKey {
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok || !a.isStructDocument(typ) {
+		return nil
+	}
+	var keys []docKey
+	for _, field := range bsonFields(st) {
+		if field.omitEmpty {
+			break
+		}
+		keys = append(keys, docKey{names: []string{field.key}, elt: pos, expr: pos})
+	}
+	return keys
+}
+
+// fieldValue returns the element of a struct literal that sets the field at index, and its value,
+// following the literals of inlined structs. Both are nil if the literal does not set the field; the
+// element is then the innermost literal, for positions.
+func (a *docAnalyzer) fieldValue(lit *ast.CompositeLit, index []int) (ast.Expr, ast.Expr) {
+	st, ok := a.typeOf(lit).Underlying().(*types.Struct)
+	if !ok {
+		return lit, nil
+	}
+	name := st.Field(index[0]).Name()
+
+	for i, elt := range lit.Elts {
+		var value ast.Expr
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if ident, ok := kv.Key.(*ast.Ident); ok && ident.Name == name {
+				value = kv.Value
+			}
+		} else if i == index[0] {
+			value = elt
+		}
+		if value == nil {
+			continue
+		}
+		if len(index) == 1 {
+			return elt, value
+		}
+		// a field of an inlined struct
+		inner := astutil.Unparen(value)
+		if u, ok := inner.(*ast.UnaryExpr); ok && u.Op == token.AND {
+			inner = astutil.Unparen(u.X)
+		}
+		if innerLit, ok := inner.(*ast.CompositeLit); ok {
+			return a.fieldValue(innerLit, index[1:])
+		}
+		return elt, nil
+	}
+	return lit, nil
+}
+
+// isEmptyValue reports whether a field value is known to be empty, e.g. "" or nil, so that the
+// field is left out by omitempty.
+func (a *docAnalyzer) isEmptyValue(value ast.Expr) bool {
+	tv, ok := a.pass.TypesInfo.Types[astutil.Unparen(value)]
+	if !ok {
+		return false
+	}
+	if tv.IsNil() {
+		return true
+	}
+	if tv.Value == nil {
+		return false
+	}
+	switch tv.Value.Kind() {
+	case constant.String:
+		return constant.StringVal(tv.Value) == ""
+	case constant.Bool:
+		return !constant.BoolVal(tv.Value)
+	case constant.Int, constant.Float:
+		return constant.Sign(tv.Value) == 0
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package common
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// The directives that suppress findings, followed by the rules they suppress and the reason:
+//
+//	//gostable:ignore GS003-unstable-stage -- the ops dashboard lists the running operations
+//
+// An ignore directive after code suppresses the findings of its line. On a line of its own, it
+// suppresses those of the statement, declaration or expression that starts on the line after its
+// comment, e.g. a function when it is in its doc comment. A file-ignore directive suppresses the
+// findings of its file.
+const (
+	ignoreDirective     = "//gostable:ignore"
+	fileIgnoreDirective = "//gostable:file-ignore"
+)
+
+// suppression is a directive that suppresses the findings of rules in a range of positions.
+type suppression struct {
+	comment *ast.Comment
+	// name is the directive without its slashes, e.g. gostable:ignore.
+	name     string
+	rules    []string
+	from, to token.Pos
+	// used holds the rules for which the directive suppressed a finding.
+	used map[string]bool
+}
+
+// suppress drops the recorded diagnostics that a directive of the package suppresses, and reports
+// the directives that have no reason, name an unknown rule, or suppress no finding.
+func (c *checker) suppress() {
+	var suppressions []*suppression
+	for _, file := range c.pass.Files {
+		suppressions = append(suppressions, c.directives(file)...)
+	}
+	if len(suppressions) == 0 {
+		return
+	}
+
+	kept := c.diagnostics[:0]
+	for _, d := range c.diagnostics {
+		suppressed := false
+		for _, s := range suppressions {
+			if s.from <= d.Pos && d.Pos < s.to && contains(s.rules, d.Category) && d.Category != ruleSuppression {
+				s.used[d.Category] = true
+				suppressed = true
+			}
+		}
+		if !suppressed {
+			kept = append(kept, d)
+		}
+	}
+	c.diagnostics = kept
+
+	for _, s := range suppressions {
+		var unused []string
+		for _, rule := range s.rules {
+			if !s.used[rule] {
+				unused = append(unused, rule)
+			}
+		}
+		if len(unused) == 0 {
+			continue
+		}
+		var fixes []analysis.SuggestedFix
+		if len(unused) == len(s.rules) {
+			if edit, ok := c.deleteComment(s.comment); ok {
+				fixes = []analysis.SuggestedFix{{Message: "Remove the directive", TextEdits: []analysis.TextEdit{edit}}}
+			}
+		}
+		c.reportDirective(s.comment.Pos(), fixes, "The %s directive for %s suppresses no finding, remove it", s.name, strings.Join(unused, ", "))
+	}
+}
+
+// directives returns the suppressions of the directives of file, and reports those that are malformed,
+// which suppress nothing.
+func (c *checker) directives(file *ast.File) []*suppression {
+	var suppressions []*suppression
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			name, rest, ok := cutDirective(comment.Text)
+			if !ok {
+				continue
+			}
+			list, reason, hasReason := strings.Cut(rest, "--")
+			var rules []string
+			for _, rule := range strings.Split(list, ",") {
+				if rule = strings.TrimSpace(rule); rule != "" {
+					rules = append(rules, rule)
+				}
+			}
+			valid := true
+			if len(rules) == 0 {
+				c.reportDirective(comment.Pos(), nil, "The %s directive names no rule, name the rules it suppresses, e.g. %s", name, ruleUnstableStage)
+				valid = false
+			}
+			for _, rule := range rules {
+				if !contains(ruleIDs, rule) || rule == ruleSuppression {
+					c.reportDirective(comment.Pos(), nil, "The %s directive names the unknown rule %s", name, rule)
+					valid = false
+				}
+			}
+			if !hasReason || strings.TrimSpace(reason) == "" {
+				c.reportDirective(comment.Pos(), nil, "The %s directive has no reason, give it after --", name)
+				valid = false
+			}
+			if !valid {
+				continue
+			}
+
+			s := &suppression{comment: comment, name: name, rules: rules, used: map[string]bool{}}
+			switch {
+			case "//"+name == fileIgnoreDirective:
+				s.from, s.to = file.FileStart, file.FileEnd
+			case c.afterCode(comment):
+				tf := c.pass.Fset.File(comment.Pos())
+				line := tf.Line(comment.Pos())
+				s.from, s.to = tf.LineStart(line), comment.Pos()
+			default:
+				s.from, s.to = c.nextNode(file, group)
+			}
+			suppressions = append(suppressions, s)
+		}
+	}
+	return suppressions
+}
+
+// cutDirective returns the name of the directive of a comment, e.g. gostable:ignore, and its text
+// after the name, if the comment is a suppression directive.
+func cutDirective(text string) (string, string, bool) {
+	for _, directive := range []string{fileIgnoreDirective, ignoreDirective} {
+		if rest, ok := strings.CutPrefix(text, directive); ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			return directive[2:], rest, true
+		}
+	}
+	return "", "", false
+}
+
+// afterCode reports whether comment follows code on its line.
+func (c *checker) afterCode(comment *ast.Comment) bool {
+	return c.codeBefore(comment.Pos())
+}
+
+// nextNode returns the range of the outermost node that starts on the line after group, or an empty
+// range if there is none, e.g. when a blank line follows.
+func (c *checker) nextNode(file *ast.File, group *ast.CommentGroup) (token.Pos, token.Pos) {
+	tf := c.pass.Fset.File(group.End())
+	line := tf.Line(group.End()) + 1
+	if line > tf.LineCount() {
+		return token.NoPos, token.NoPos
+	}
+	start := tf.LineStart(line)
+	var from, to token.Pos
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n.(type) {
+		case nil, *ast.CommentGroup, *ast.Comment:
+			return false
+		}
+		if n.End() < start {
+			return false
+		}
+		if tf.Line(n.Pos()) == line && (from == token.NoPos || n.Pos() < from || n.Pos() == from && n.End() > to) {
+			from, to = n.Pos(), n.End()
+		}
+		return true
+	})
+	return from, to
+}
+
+// deleteComment returns the edit that deletes comment: its lines when it is on lines of its own, or
+// the comment after the code of its line.
+func (c *checker) deleteComment(comment *ast.Comment) (analysis.TextEdit, bool) {
+	if c.afterCode(comment) {
+		return analysis.TextEdit{Pos: comment.Pos(), End: comment.End()}, true
+	}
+	return c.deleteLines(comment)
+}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// The directives are placed from the positions of the syntax tree, not from the source files, which
+// some drivers do not give the passes to read.
+func TestDirectivesWithoutReadFile(t *testing.T) {
+	const src = `package p
+
+func f() {
+	g() //gostable:ignore GS001-unstable-method -- trailing
+	//gostable:ignore GS001-unstable-method -- own line
+	g()
+	g()
+}
+
+func g() {}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &checker{pass: &analysis.Pass{Fset: fset, Files: []*ast.File{file}}}
+
+	suppressions := c.directives(file)
+	if len(suppressions) != 2 {
+		t.Fatalf("got %d suppressions, want 2", len(suppressions))
+	}
+	for i, want := range []struct {
+		line       int
+		editsLines bool
+	}{
+		{line: 4, editsLines: false},
+		{line: 6, editsLines: true},
+	} {
+		s := suppressions[i]
+		from, to := fset.Position(s.from), fset.Position(s.to)
+		if !s.from.IsValid() || from.Line != want.line || to.Line != want.line {
+			t.Errorf("%s suppresses lines %d to %d, want line %d", s.comment.Text, from.Line, to.Line, want.line)
+		}
+
+		edit, ok := c.deleteComment(s.comment)
+		if !ok {
+			t.Errorf("%s cannot be deleted", s.comment.Text)
+			continue
+		}
+		if editsLines := edit.Pos != s.comment.Pos(); editsLines != want.editsLines {
+			t.Errorf("deleting %s deletes its lines: %v, want %v", s.comment.Text, editsLines, want.editsLines)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package common
+
+import (
+	"go/types"
+)
+
+// commandMethods are the driver methods that run the command document passed as their 2nd argument.
+var commandMethods = map[string][]string{
+	"Client":   {"RunCommand"},
+	"Database": {"RunCommand", "RunCommandCursor"},
+}
+
+// symbolTable holds the objects of the driver packages that the package imports, directly or not,
+// which the analyzer looks for. Uses are matched on the identity of their objects, through the type
+// information, so a type of another package named Collection is not mistaken for the driver's, and
+// an alias of a driver type is not missed.
+type symbolTable struct {
+	// rules maps the unsupported methods (*types.Func), struct fields (*types.Var) and option
+	// constants (*types.Const) to the rule that makes them unsupported on the target server version,
+	// of the catalog or of the command model.
+	rules map[types.Object]SymbolRule
+	// commands holds the methods of commandMethods.
+	commands map[*types.Func]bool
+	// pipelines maps the methods of pipelineMethods to the index of their pipeline argument.
+	pipelines map[*types.Func]int
+	// connects holds the functions of connectFuncs.
+	connects map[*types.Func]bool
+}
+
+// symbols returns the symbol table of the package, building it on first use.
+func (c *checker) symbols() *symbolTable {
+	if c.symbolTable != nil {
+		return c.symbolTable
+	}
+	t := &symbolTable{
+		rules:     map[types.Object]SymbolRule{},
+		commands:  map[*types.Func]bool{},
+		pipelines: map[*types.Func]int{},
+		connects:  map[*types.Func]bool{},
+	}
+	c.symbolTable = t
+
+	for _, pkg := range c.driverPackages() {
+		for _, rule := range c.cat.methodRules() {
+			if rule.Package != pkg.Path() || !rule.unsupportedOn(c.cat.serverVersion) {
+				continue
+			}
+			for _, name := range rule.Names {
+				if fn := lookupMethod(pkg, rule.Type, name); fn != nil {
+					t.addRule(fn, rule)
+				}
+			}
+		}
+		for _, rule := range c.cat.fieldRules() {
+			if rule.Package != pkg.Path() || !rule.unsupportedOn(c.cat.serverVersion) {
+				continue
+			}
+			for _, name := range rule.Names {
+				if obj := lookupField(pkg, rule.Type, name); obj != nil {
+					t.addRule(obj, rule)
+				}
+			}
+		}
+
+		if pkg.Path() != c.cat.driverModule(pkg.Path())+"/"+mongoPkgName {
+			continue
+		}
+		for typeName, names := range commandMethods {
+			for _, name := range names {
+				if fn := lookupMethod(pkg, typeName, name); fn != nil {
+					t.commands[fn] = true
+				}
+			}
+		}
+		for _, name := range connectFuncs {
+			if fn, ok := pkg.Scope().Lookup(name).(*types.Func); ok {
+				t.connects[fn] = true
+			}
+		}
+		for typeName, methods := range pipelineMethods {
+			for name, argIndex := range methods {
+				if fn := lookupMethod(pkg, typeName, name); fn != nil {
+					t.pipelines[fn] = argIndex
+				}
+			}
+		}
+	}
+	return t
+}
+
+// addRule records the rule of obj, keeping the first rule when several name it.
+func (t *symbolTable) addRule(obj types.Object, rule SymbolRule) {
+	if _, ok := t.rules[obj]; !ok {
+		t.rules[obj] = rule
+	}
+}
+
+// rule returns the rule that makes obj unsupported, if any.
+func (t *symbolTable) rule(obj types.Object) (SymbolRule, bool) {
+	if obj == nil {
+		return SymbolRule{}, false
+	}
+	rule, ok := t.rules[obj]
+	return rule, ok
+}
+
+// lookupMethod returns the method typeName.name of pkg, declared on the type or its pointer, or nil.
+func lookupMethod(pkg *types.Package, typeName, name string) *types.Func {
+	typ, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(typ.Type()), false, pkg, name)
+	fn, _ := obj.(*types.Func)
+	return fn
+}
+
+// lookupField returns the field typeName.name of a struct of pkg or, for the other types, the constant
+// name of that type declared in pkg, such as the CursorType constant Tailable, or nil.
+func lookupField(pkg *types.Package, typeName, name string) types.Object {
+	typ, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	if _, ok := typ.Type().Underlying().(*types.Struct); ok {
+		obj, _, _ := types.LookupFieldOrMethod(typ.Type(), false, pkg, name)
+		if field, ok := obj.(*types.Var); ok && field.IsField() {
+			return field
+		}
+		return nil
+	}
+	if constant, ok := pkg.Scope().Lookup(name).(*types.Const); ok && types.Identical(constant.Type(), typ.Type()) {
+		return constant
+	}
+	return nil
+}
+
+// driverPackages returns the packages of the driver that the package imports, directly or not.
+func (c *checker) driverPackages() []*types.Package {
+	var pkgs []*types.Package
+	seen := map[*types.Package]bool{}
+	var visit func(pkg *types.Package)
+	visit = func(pkg *types.Package) {
+		if seen[pkg] {
+			return
+		}
+		seen[pkg] = true
+		if c.cat.driverModule(pkg.Path()) != "" {
+			pkgs = append(pkgs, pkg)
+		}
+		for _, imported := range pkg.Imports() {
+			visit(imported)
+		}
+	}
+	visit(c.pass.Pkg)
+	return pkgs
+}
@@ -0,0 +1,304 @@
+package common
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/vta"
+	"golang.org/x/tools/go/ssa"
+)
+
+// maxValues bounds the number of values tracked for one expression, e.g. through concatenations of phis.
+const maxValues = 64
+
+// valueAnalyzer finds the possible values of string expressions such as stage keys and command names
+// through the SSA form of the package: constants, local and package variables, concatenations with +,
+// and the arguments of the calls within the package to the function that declares a parameter.
+type valueAnalyzer struct {
+	pass *analysis.Pass
+	pkg  *ssa.Package
+
+	// stores maps an address, a package variable or a local that escaped, to the values stored there.
+	stores map[ssa.Value][]ssa.Value
+	// calls maps a function to the call sites that call it statically.
+	calls map[*ssa.Function][]*ssa.CallCommon
+	// closures maps an anonymous function to the closures made of it.
+	closures map[*ssa.Function][]*ssa.MakeClosure
+	// callGraph is built by the first lookup of callees.
+	callGraph *callgraph.Graph
+}
+
+// newValueAnalyzer builds the SSA form of the package. Unlike buildssa, it keeps the debug information
+// that maps expressions to values.
+func newValueAnalyzer(pass *analysis.Pass) *valueAnalyzer {
+	prog := ssa.NewProgram(pass.Fset, ssa.GlobalDebug)
+	for _, p := range pass.Pkg.Imports() {
+		prog.CreatePackage(p, nil, nil, true)
+	}
+	pkg := prog.CreatePackage(pass.Pkg, pass.Files, pass.TypesInfo, false)
+	pkg.Build()
+
+	a := &valueAnalyzer{
+		pass:     pass,
+		pkg:      pkg,
+		stores:   map[ssa.Value][]ssa.Value{},
+		calls:    map[*ssa.Function][]*ssa.CallCommon{},
+		closures: map[*ssa.Function][]*ssa.MakeClosure{},
+	}
+	for _, fn := range a.functions() {
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				switch instr := instr.(type) {
+				case *ssa.Store:
+					a.stores[instr.Addr] = append(a.stores[instr.Addr], instr.Val)
+				case *ssa.MakeClosure:
+					if anon, ok := instr.Fn.(*ssa.Function); ok {
+						a.closures[anon] = append(a.closures[anon], instr)
+					}
+				}
+				if call, ok := instr.(ssa.CallInstruction); ok {
+					if callee := call.Common().StaticCallee(); callee != nil {
+						a.calls[callee] = append(a.calls[callee], call.Common())
+					}
+				}
+			}
+		}
+	}
+	return a
+}
+
+// functions returns the functions declared in the package, including the package initializer
+// and the function literals.
+func (a *valueAnalyzer) functions() []*ssa.Function {
+	var funcs []*ssa.Function
+	var addAnons func(fn *ssa.Function)
+	addAnons = func(fn *ssa.Function) {
+		funcs = append(funcs, fn)
+		for _, anon := range fn.AnonFuncs {
+			addAnons(anon)
+		}
+	}
+
+	if init := a.pkg.Func("init"); init != nil {
+		addAnons(init)
+	}
+	for _, file := range a.pass.Files {
+		for _, decl := range file.Decls {
+			if decl, ok := decl.(*ast.FuncDecl); ok {
+				if obj, ok := a.pass.TypesInfo.Defs[decl.Name].(*types.Func); ok {
+					if fn := a.pkg.Prog.FuncValue(obj); fn != nil {
+						addAnons(fn)
+					}
+				}
+			}
+		}
+	}
+	return funcs
+}
+
+// stringValues returns the values that the non-constant string expression expr may have,
+// or nil if they could not be determined.
+func (a *valueAnalyzer) stringValues(expr ast.Expr) []string {
+	expr = astutil.Unparen(expr)
+	fn := a.enclosingFunction(expr)
+	if fn == nil {
+		return nil
+	}
+	v, isAddr := fn.ValueForExpr(expr)
+	if v == nil {
+		return nil
+	}
+
+	seen := map[ssa.Value]bool{}
+	if isAddr {
+		return a.storedStrings(v, seen)
+	}
+	return a.strings(v, seen)
+}
+
+// enclosingFunction returns the SSA function that contains node, the package initializer for the
+// initializers of package variables.
+func (a *valueAnalyzer) enclosingFunction(node ast.Node) *ssa.Function {
+	for _, file := range a.pass.Files {
+		if file.Pos() <= node.Pos() && node.Pos() < file.End() {
+			path, _ := astutil.PathEnclosingInterval(file, node.Pos(), node.End())
+			return ssa.EnclosingFunction(a.pkg, path)
+		}
+	}
+	return nil
+}
+
+// callees returns the functions that call, a call of a function value or of an interface method, may
+// call according to the VTA (variable type analysis) call graph of the package.
+func (a *valueAnalyzer) callees(call *ast.CallExpr) []*ssa.Function {
+	fn := a.enclosingFunction(call)
+	if fn == nil {
+		return nil
+	}
+	var callees []*ssa.Function
+	for _, edge := range a.graph().Nodes[fn].Out {
+		if edge.Site != nil && edge.Site.Pos() == call.Lparen {
+			callees = append(callees, edge.Callee.Func)
+		}
+	}
+	return callees
+}
+
+// wrappedCallees returns the functions that wrapper, the thunk of a method expression or the bound
+// function of a method value, may call according to the VTA call graph.
+func (a *valueAnalyzer) wrappedCallees(wrapper *ssa.Function) []*ssa.Function {
+	node := a.graph().Nodes[wrapper]
+	if node == nil {
+		return nil
+	}
+	var callees []*ssa.Function
+	for _, edge := range node.Out {
+		callees = append(callees, edge.Callee.Func)
+	}
+	return callees
+}
+
+// graph returns the VTA call graph of the functions of the package and of the wrappers they call or
+// bind, built by the first lookup of callees.
+func (a *valueAnalyzer) graph() *callgraph.Graph {
+	if a.callGraph != nil {
+		return a.callGraph
+	}
+	funcs := map[*ssa.Function]bool{}
+	for _, fn := range a.functions() {
+		funcs[fn] = true
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				var wrapper *ssa.Function
+				switch instr := instr.(type) {
+				case *ssa.MakeClosure:
+					wrapper, _ = instr.Fn.(*ssa.Function)
+				case ssa.CallInstruction:
+					wrapper = instr.Common().StaticCallee()
+				}
+				if wrapper != nil && wrapper.Synthetic != "" {
+					funcs[wrapper] = true
+				}
+			}
+		}
+	}
+	a.callGraph = vta.CallGraph(funcs, cha.CallGraph(a.pkg.Prog))
+	return a.callGraph
+}
+
+// strings returns the string values that v may have.
+func (a *valueAnalyzer) strings(v ssa.Value, seen map[ssa.Value]bool) []string {
+	if seen[v] {
+		return nil
+	}
+	seen[v] = true
+	defer delete(seen, v)
+
+	switch v := v.(type) {
+	case *ssa.Const:
+		if v.Value != nil && v.Value.Kind() == constant.String {
+			return []string{constant.StringVal(v.Value)}
+		}
+
+	case *ssa.BinOp:
+		if v.Op != token.ADD {
+			return nil
+		}
+		var values []string
+		for _, x := range a.strings(v.X, seen) {
+			for _, y := range a.strings(v.Y, seen) {
+				if len(values) < maxValues {
+					values = appendMissing(values, x+y)
+				}
+			}
+		}
+		return values
+
+	case *ssa.Phi:
+		var values []string
+		for _, edge := range v.Edges {
+			values = appendMissing(values, a.strings(edge, seen)...)
+		}
+		return values
+
+	case *ssa.ChangeType:
+		return a.strings(v.X, seen)
+
+	case *ssa.Convert:
+		// Conversions between string types only, not from byte slices or runes
+		if basic, ok := v.X.Type().Underlying().(*types.Basic); ok && basic.Info()&types.IsString != 0 {
+			return a.strings(v.X, seen)
+		}
+
+	case *ssa.UnOp:
+		if v.Op == token.MUL {
+			return a.storedStrings(v.X, seen)
+		}
+
+	case *ssa.Parameter:
+		fn := v.Parent()
+		index := -1
+		for i, param := range fn.Params {
+			if param == v {
+				index = i
+			}
+		}
+		var values []string
+		for _, call := range a.calls[fn] {
+			if index >= 0 && index < len(call.Args) {
+				values = appendMissing(values, a.strings(call.Args[index], seen)...)
+			}
+		}
+		return values
+
+	case *ssa.FreeVar:
+		var values []string
+		for _, binding := range a.bindings(v) {
+			values = appendMissing(values, a.strings(binding, seen)...)
+		}
+		return values
+	}
+	return nil
+}
+
+// storedStrings returns the string values stored at addr, a package variable, a local variable that
+// escaped to the heap, or a variable captured by a closure.
+func (a *valueAnalyzer) storedStrings(addr ssa.Value, seen map[ssa.Value]bool) []string {
+	if free, ok := addr.(*ssa.FreeVar); ok {
+		var values []string
+		for _, binding := range a.bindings(free) {
+			values = appendMissing(values, a.storedStrings(binding, seen)...)
+		}
+		return values
+	}
+
+	var values []string
+	for _, stored := range a.stores[addr] {
+		values = appendMissing(values, a.strings(stored, seen)...)
+	}
+	return values
+}
+
+// bindings returns the values bound to a free variable by the closures made of its function.
+func (a *valueAnalyzer) bindings(free *ssa.FreeVar) []ssa.Value {
+	fn := free.Parent()
+	index := -1
+	for i, fv := range fn.FreeVars {
+		if fv == free {
+			index = i
+		}
+	}
+	var bindings []ssa.Value
+	for _, closure := range a.closures[fn] {
+		if index >= 0 && index < len(closure.Bindings) {
+			bindings = append(bindings, closure.Bindings[index])
+		}
+	}
+	return bindings
+}
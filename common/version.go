@@ -0,0 +1,114 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// serverVersion is a parsed MongoDB server version such as 5.0.9.
+type serverVersion []int
+
+func parseServerVersion(s string) (serverVersion, error) {
+	var v serverVersion
+	for _, part := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid server version %q", s)
+		}
+		v = append(v, n)
+	}
+	return v, nil
+}
+
+// part returns the i-th component of the version. Missing components count as zero, so 6.0 == 6.0.0.
+func (v serverVersion) part(i int) int {
+	if i < len(v) {
+		return v[i]
+	}
+	return 0
+}
+
+// compare returns -1, 0 or 1.
+func (v serverVersion) compare(other serverVersion) int {
+	for i := 0; i < len(v) || i < len(other); i++ {
+		if a, b := v.part(i), other.part(i); a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// sameRelease reports whether both versions belong to the same major.minor release series.
+func (v serverVersion) sameRelease(other serverVersion) bool {
+	return v.part(0) == other.part(0) && v.part(1) == other.part(1)
+}
+
+func (v serverVersion) String() string {
+	parts := make([]string, len(v))
+	for i, n := range v {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ".")
+}
+
+// versionList holds the server versions in which a catalog entry became part of the Stable API.
+// More than one version means that the change was backported, e.g. [5.0.9, 6.0].
+// It is written in the catalog either as a single version or as a list.
+type versionList []serverVersion
+
+func (l *versionList) UnmarshalYAML(node *yaml.Node) error {
+	var values []string
+	switch node.Kind {
+	case yaml.ScalarNode:
+		values = []string{node.Value}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			values = append(values, item.Value)
+		}
+	default:
+		return fmt.Errorf("line %d: since must be a version or a list of versions", node.Line)
+	}
+
+	for _, s := range values {
+		v, err := parseServerVersion(s)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", node.Line, err)
+		}
+		*l = append(*l, v)
+	}
+	return nil
+}
+
+// includes reports whether server version v has the entry. That is the case when v is at least the
+// latest version in the list, or when v is in the release series of a backport and not older than it.
+// An empty list, or an unknown server version, means the entry is always included.
+func (l versionList) includes(v serverVersion) bool {
+	if len(l) == 0 || v == nil {
+		return true
+	}
+
+	latest := l[0]
+	for _, since := range l {
+		if since.compare(latest) > 0 {
+			latest = since
+		}
+		if since.sameRelease(v) && v.compare(since) >= 0 {
+			return true
+		}
+	}
+	return v.compare(latest) >= 0
+}
+
+func (l versionList) String() string {
+	parts := make([]string, len(l))
+	for i, v := range l {
+		parts[i] = v.String()
+	}
+	return strings.Join(parts, ", ")
+}
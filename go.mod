@@ -1,10 +1,14 @@
 module gostable
 
-go 1.21
+go 1.22
 
 toolchain go1.22.2
 
-require golang.org/x/tools v0.21.0
+require (
+	github.com/golangci/plugin-module-register v0.1.1
+	golang.org/x/tools v0.21.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	golang.org/x/mod v0.17.0 // indirect
@@ -0,0 +1,83 @@
+// Package golangci registers gostable as a module plugin of golangci-lint, which is compiled into a
+// custom golangci-lint binary with the toolchain and dependencies of that binary, unlike the plugin
+// built with -buildmode=plugin. The binary is built by golangci-lint custom from .custom-gcl.yml:
+//
+//	version: v1.59.1
+//	plugins:
+//	  - module: gostable
+//	    import: gostable/golangci
+//	    path: ../gostable
+//
+// and the linter is enabled in .golangci.yml, with the settings of the flags of the standalone binary:
+//
+//	linters-settings:
+//	  custom:
+//	    gostable:
+//	      type: module
+//	      settings:
+//	        catalog: org-overlay.yaml
+//	        server-version: 5.0.3
+//	        severity: warning
+//	        exclude: [GS005-unresolved-command]
+package golangci
+
+import (
+	"fmt"
+	"strings"
+
+	"gostable/common"
+
+	"github.com/golangci/plugin-module-register/register"
+	"golang.org/x/tools/go/analysis"
+)
+
+func init() {
+	register.Plugin("gostable", New)
+}
+
+// Settings are the settings of the linter in .golangci.yml. Each is the flag of the same name of the
+// standalone binary.
+type Settings struct {
+	Catalog       string   `json:"catalog"`
+	ServerVersion string   `json:"server-version"`
+	Severity      string   `json:"severity"`
+	Exclude       []string `json:"exclude"`
+}
+
+type plugin struct{}
+
+// New returns the plugin with settings, which it sets as the flags of the analyzer.
+func New(settings any) (register.LinterPlugin, error) {
+	s, err := register.DecodeSettings[Settings](settings)
+	if err != nil {
+		return nil, fmt.Errorf("gostable: %v", err)
+	}
+	flags := []struct{ name, value string }{
+		{"catalog", s.Catalog},
+		{"server-version", s.ServerVersion},
+		{"severity", s.Severity},
+		{"exclude", strings.Join(s.Exclude, ",")},
+	}
+	for _, flag := range flags {
+		if flag.value == "" {
+			continue
+		}
+		if err := common.StableAnalyzer.Flags.Set(flag.name, flag.value); err != nil {
+			return nil, fmt.Errorf("gostable: %s: %v", flag.name, err)
+		}
+	}
+	if err := common.CheckFlags(); err != nil {
+		return nil, fmt.Errorf("gostable: %v", err)
+	}
+	return &plugin{}, nil
+}
+
+func (*plugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	return []*analysis.Analyzer{common.StableAnalyzer}, nil
+}
+
+// GetLoadMode returns the mode of the packages, which are type checked as the analyzer resolves the
+// driver calls.
+func (*plugin) GetLoadMode() string {
+	return register.LoadModeTypesInfo
+}
@@ -0,0 +1,49 @@
+package golangci
+
+import (
+	"strings"
+	"testing"
+
+	"gostable/common"
+)
+
+func TestNew(t *testing.T) {
+	// the settings as golangci-lint decodes them from .golangci.yml
+	settings := map[string]any{
+		"catalog":        "../testdata/catalog/overlay.yaml",
+		"server-version": "5.0.3",
+		"severity":       "warning",
+		"exclude":        []any{"GS005-unresolved-command", "GS008-client-config"},
+	}
+	p, err := New(settings)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	want := map[string]string{
+		"catalog":        "../testdata/catalog/overlay.yaml",
+		"server-version": "5.0.3",
+		"severity":       "warning",
+		"exclude":        "GS005-unresolved-command,GS008-client-config",
+	}
+	for name, value := range want {
+		if got := common.StableAnalyzer.Flags.Lookup(name).Value.String(); got != value {
+			t.Errorf("flag -%s = %q, want %q", name, got, value)
+		}
+	}
+
+	analyzers, err := p.BuildAnalyzers()
+	if err != nil {
+		t.Fatalf("BuildAnalyzers: %v", err)
+	}
+	if len(analyzers) != 1 || analyzers[0] != common.StableAnalyzer {
+		t.Errorf("BuildAnalyzers = %v, want the gostable analyzer", analyzers)
+	}
+}
+
+func TestNewUnknownSetting(t *testing.T) {
+	_, err := New(map[string]any{"server-versions": "5.0.3"})
+	if err == nil || !strings.Contains(err.Error(), "server-versions") {
+		t.Errorf("New with an unknown setting returned %v, want an error naming it", err)
+	}
+}
@@ -0,0 +1,261 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultBaseline is the file that gostable baseline write writes when -baseline is not set.
+const DefaultBaseline = "gostable-baseline.json"
+
+// baselineVersion is the version of the baseline file. It changes if the keys of the entries do.
+const baselineVersion = 1
+
+// baseline is the file of the findings that a module accepts, which later runs do not report again.
+type baseline struct {
+	Version int             `json:"version"`
+	Entries []baselineEntry `json:"findings"`
+}
+
+// baselineEntry is a finding of the baseline, or the findings that have the same key. The key has no
+// line number, so that the entry is kept when unrelated code is added or removed before it.
+type baselineEntry struct {
+	Rule string `json:"rule"`
+	// Package is the path of the package of the finding.
+	Package string `json:"package"`
+	// Function is the function that encloses the finding, e.g. Repo.Find for a method, or "" for a
+	// finding outside of functions.
+	Function string `json:"function,omitempty"`
+	// Snippet is the line of the finding with its spaces collapsed.
+	Snippet string `json:"snippet"`
+	// Count is the number of findings with the key.
+	Count int `json:"count"`
+}
+
+type baselineKey struct {
+	Rule, Package, Function, Snippet string
+}
+
+func (e baselineEntry) key() baselineKey {
+	return baselineKey{e.Rule, e.Package, e.Function, e.Snippet}
+}
+
+func (e baselineEntry) String() string {
+	scope := e.Package
+	if e.Function != "" {
+		scope += "." + e.Function
+	}
+	s := fmt.Sprintf("%s in %s: %s", e.Rule, scope, e.Snippet)
+	if e.Count > 1 {
+		s += fmt.Sprintf(" (%d findings)", e.Count)
+	}
+	return s
+}
+
+// BaselineMain runs gostable baseline with args, the command line after baseline, and returns the
+// exit code. Its only command is write, which analyzes the packages and writes their findings to the
+// baseline file:
+//
+//	gostable baseline write [-baseline=file] [flags] packages
+func BaselineMain(args []string) int {
+	if len(args) == 0 || args[0] != "write" {
+		fmt.Fprintln(os.Stderr, "usage: gostable baseline write [-baseline=file] [flags] packages")
+		return 2
+	}
+	file, args := BaselineFlag(args[1:])
+	if file == "" {
+		file = DefaultBaseline
+	}
+	findings, failures, err := analyze(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gostable: %v\n", err)
+		return 1
+	}
+	// A package that failed has no findings, and later runs would report all of its findings as new.
+	if len(failures) > 0 {
+		for _, failure := range failures {
+			fmt.Fprintf(os.Stderr, "gostable: %s\n", failure)
+		}
+		fmt.Fprintf(os.Stderr, "gostable: %s is not written as the analysis failed\n", file)
+		return 1
+	}
+	root, err := moduleRoot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gostable: %v\n", err)
+		return 1
+	}
+
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(newBaseline(findings, newSources(root))); err != nil {
+		fmt.Fprintf(os.Stderr, "gostable: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(file, data.Bytes(), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "gostable: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "gostable: wrote %d findings to %s\n", len(findings), file)
+	return 0
+}
+
+// newBaseline returns the baseline of findings, whose entries are sorted by key.
+func newBaseline(findings []Finding, src *sources) *baseline {
+	keys := newBaselineKeys(src)
+	counts := map[baselineKey]int{}
+	for _, finding := range findings {
+		counts[keys.key(finding)]++
+	}
+	base := &baseline{Version: baselineVersion, Entries: []baselineEntry{}}
+	for k, n := range counts {
+		base.Entries = append(base.Entries, baselineEntry{Rule: k.Rule, Package: k.Package, Function: k.Function, Snippet: k.Snippet, Count: n})
+	}
+	sort.Slice(base.Entries, func(i, j int) bool {
+		a, b := base.Entries[i], base.Entries[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Function != b.Function {
+			return a.Function < b.Function
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Snippet < b.Snippet
+	})
+	return base
+}
+
+// readBaseline reads a baseline file.
+func readBaseline(file string) (*baseline, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading the baseline: %v", err)
+	}
+	var base baseline
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, fmt.Errorf("reading the baseline %s: %v", file, err)
+	}
+	if base.Version != baselineVersion {
+		return nil, fmt.Errorf("the baseline %s has version %d, want %d; write it again with gostable baseline write",
+			file, base.Version, baselineVersion)
+	}
+	return &base, nil
+}
+
+// compare returns the findings that are not in the baseline, and the entries of the baseline that
+// have fewer findings than they count, with the number of the missing ones. The entries of the
+// packages that failed are not compared, as their findings are unknown.
+func (b *baseline) compare(findings []Finding, failures []string, src *sources) ([]Finding, []baselineEntry) {
+	remaining := map[baselineKey]int{}
+	for _, entry := range b.Entries {
+		remaining[entry.key()] += entry.Count
+	}
+
+	keys := newBaselineKeys(src)
+	var added []Finding
+	for _, finding := range findings {
+		k := keys.key(finding)
+		if remaining[k] > 0 {
+			remaining[k]--
+			continue
+		}
+		added = append(added, finding)
+	}
+
+	failed := map[string]bool{}
+	for _, failure := range failures {
+		pkg, _, _ := strings.Cut(failure, ": ")
+		failed[packagePath(pkg)] = true
+	}
+	var fixed []baselineEntry
+	for _, entry := range b.Entries {
+		k := entry.key()
+		if n := remaining[k]; n > 0 && !failed[entry.Package] {
+			// An entry may be listed twice in a baseline that was merged by hand.
+			remaining[k] = 0
+			entry.Count = n
+			fixed = append(fixed, entry)
+		}
+	}
+	return added, fixed
+}
+
+// baselineKeys computes the keys of findings, parsing their files for the functions that enclose them.
+type baselineKeys struct {
+	src   *sources
+	fset  *token.FileSet
+	files map[string]*ast.File
+}
+
+func newBaselineKeys(src *sources) *baselineKeys {
+	return &baselineKeys{src: src, fset: token.NewFileSet(), files: map[string]*ast.File{}}
+}
+
+func (k *baselineKeys) key(finding Finding) baselineKey {
+	return baselineKey{
+		Rule:     finding.Rule,
+		Package:  finding.Package,
+		Function: k.function(finding.Location),
+		Snippet:  k.src.lineText(finding.Location),
+	}
+}
+
+// function returns the name of the function declaration that encloses loc, with the type of its
+// receiver for a method, or "" if there is none or the file cannot be parsed. A function literal is
+// part of the declaration that it is in.
+func (k *baselineKeys) function(loc Location) string {
+	f, ok := k.files[loc.File]
+	if !ok {
+		f, _ = parser.ParseFile(k.fset, loc.File, nil, parser.SkipObjectResolution)
+		k.files[loc.File] = f
+	}
+	if f == nil {
+		return ""
+	}
+	tf := k.fset.File(f.Pos())
+	if loc.Line < 1 || loc.Line > tf.LineCount() {
+		return ""
+	}
+	pos := tf.LineStart(loc.Line) + token.Pos(loc.Column-1)
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || pos < fn.Pos() || pos >= fn.End() {
+			continue
+		}
+		if fn.Recv == nil || len(fn.Recv.List) == 0 {
+			return fn.Name.Name
+		}
+		return receiverType(fn.Recv.List[0].Type) + "." + fn.Name.Name
+	}
+	return ""
+}
+
+// receiverType returns the name of the type of a receiver, without its pointer or type parameters.
+func receiverType(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
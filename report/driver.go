@@ -0,0 +1,176 @@
+package report
+
+import (
+	"flag"
+	"fmt"
+	"go/types"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gostable/common"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/packages"
+)
+
+// packageResult is the result of the analyzer on a package of the command line, or the error of the
+// package when it could not be analyzed.
+type packageResult struct {
+	pkg    *packages.Package
+	result *common.Result
+	err    error
+}
+
+// analyzePackages runs the analyzer on the packages of args, the flags of the analyzer and the package
+// patterns, as singlechecker does but in process, so that the reports read the results of the
+// analyzer rather than its diagnostics. The dependencies are analyzed first, for their facts. It
+// returns the results on the packages of args, in the order of their IDs.
+func analyzePackages(args []string) ([]packageResult, error) {
+	flags := flag.NewFlagSet("gostable", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	tests := flags.Bool("test", true, "indicates whether test files should be analyzed, too")
+	common.StableAnalyzer.Flags.VisitAll(func(f *flag.Flag) {
+		flags.Var(f.Value, f.Name, f.Usage)
+	})
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := &packages.Config{Mode: packages.LoadAllSyntax, Tests: *tests}
+	initial, err := packages.Load(cfg, flags.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	if len(initial) == 0 {
+		return nil, fmt.Errorf("%s matched no packages", strings.Join(flags.Args(), " "))
+	}
+	// The type errors fail the analysis of their packages only, as with singlechecker.
+	if packages.PrintErrors(initial) > 0 {
+		listed := true
+		packages.Visit(initial, nil, func(pkg *packages.Package) {
+			for _, err := range pkg.Errors {
+				listed = listed && (err.Kind == packages.TypeError || err.Kind == packages.ParseError)
+			}
+		})
+		if !listed {
+			return nil, fmt.Errorf("errors during loading")
+		}
+	}
+
+	d := &driver{facts: map[factKey]analysis.Fact{}, results: map[*packages.Package]packageResult{}}
+	packages.Visit(initial, nil, func(pkg *packages.Package) {
+		d.results[pkg] = d.analyze(pkg)
+	})
+
+	var results []packageResult
+	for _, pkg := range initial {
+		results = append(results, d.results[pkg])
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].pkg.ID < results[j].pkg.ID
+	})
+	return results, nil
+}
+
+// driver holds the facts and results of the packages analyzed so far.
+type driver struct {
+	facts   map[factKey]analysis.Fact
+	results map[*packages.Package]packageResult
+}
+
+// factKey identifies a fact: of an object, or of a package when obj is nil.
+type factKey struct {
+	obj types.Object
+	pkg *types.Package
+	typ reflect.Type
+}
+
+// analyze runs the inspect analyzer and then gostable on pkg, whose imports are analyzed.
+func (d *driver) analyze(pkg *packages.Package) packageResult {
+	var failed []string
+	for _, imp := range pkg.Imports {
+		if d.results[imp].err != nil {
+			failed = append(failed, common.StableAnalyzer.Name+"@"+imp.ID)
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return packageResult{pkg: pkg, err: fmt.Errorf("failed prerequisites: %s", strings.Join(failed, ", "))}
+	}
+	if pkg.IllTyped {
+		return packageResult{pkg: pkg, err: fmt.Errorf("analysis skipped due to errors in package")}
+	}
+
+	pass := &analysis.Pass{
+		Fset:         pkg.Fset,
+		Files:        pkg.Syntax,
+		OtherFiles:   pkg.OtherFiles,
+		IgnoredFiles: pkg.IgnoredFiles,
+		Pkg:          pkg.Types,
+		TypesInfo:    pkg.TypesInfo,
+		TypesSizes:   pkg.TypesSizes,
+		Report:       func(analysis.Diagnostic) {},
+		ResultOf:     map[*analysis.Analyzer]interface{}{},
+		ReadFile:     packageFileReader(pkg),
+		ImportObjectFact: func(obj types.Object, fact analysis.Fact) bool {
+			return d.importFact(factKey{obj: obj, typ: reflect.TypeOf(fact)}, fact)
+		},
+		ImportPackageFact: func(p *types.Package, fact analysis.Fact) bool {
+			return d.importFact(factKey{pkg: p, typ: reflect.TypeOf(fact)}, fact)
+		},
+		ExportObjectFact: func(obj types.Object, fact analysis.Fact) {
+			d.facts[factKey{obj: obj, typ: reflect.TypeOf(fact)}] = fact
+		},
+		ExportPackageFact: func(fact analysis.Fact) {
+			d.facts[factKey{pkg: pkg.Types, typ: reflect.TypeOf(fact)}] = fact
+		},
+	}
+
+	for _, a := range []*analysis.Analyzer{inspect.Analyzer, common.StableAnalyzer} {
+		pass.Analyzer = a
+		result, err := a.Run(pass)
+		if err != nil {
+			return packageResult{pkg: pkg, err: err}
+		}
+		pass.ResultOf[a] = result
+	}
+	return packageResult{pkg: pkg, result: pass.ResultOf[common.StableAnalyzer].(*common.Result)}
+}
+
+// importFact copies the fact of key to fact, if there is one.
+func (d *driver) importFact(key factKey, fact analysis.Fact) bool {
+	exported, ok := d.facts[key]
+	if ok {
+		reflect.ValueOf(fact).Elem().Set(reflect.ValueOf(exported).Elem())
+	}
+	return ok
+}
+
+// packageFileReader returns the ReadFile function of the passes of pkg, which reads the files of the
+// package only.
+func packageFileReader(pkg *packages.Package) func(string) ([]byte, error) {
+	return func(filename string) ([]byte, error) {
+		for _, files := range [][]string{pkg.CompiledGoFiles, pkg.GoFiles, pkg.OtherFiles, pkg.IgnoredFiles} {
+			if contains(files, filename) {
+				return os.ReadFile(filename)
+			}
+		}
+		return nil, fmt.Errorf("%s is not a file of package %s", filename, pkg.ID)
+	}
+}
+
+// packageFailures returns the errors of the packages of results that could not be analyzed, each after
+// the ID of its package.
+func packageFailures(results []packageResult) []string {
+	var failures []string
+	for _, r := range results {
+		if r.err != nil {
+			failures = append(failures, r.pkg.ID+": "+r.err.Error())
+		}
+	}
+	return failures
+}
@@ -0,0 +1,160 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gostable/common"
+)
+
+// InventoryFormats are the values of the -inventory flag.
+var InventoryFormats = []string{"csv", "json"}
+
+// InventoryFlag returns the value of the -inventory flag in args, "" if it is not set, and args without it.
+func InventoryFlag(args []string) (string, []string) {
+	return cutFlag(args, "inventory")
+}
+
+// inventoryEntry is an entry of the inventory: the calls of a driver method in a function that have the
+// same details. There are no positions, so that the inventories of two releases can be diffed.
+type inventoryEntry struct {
+	// Package is the path of the package of the calls, and File their file relative to the root of
+	// the module.
+	Package string `json:"package"`
+	File    string `json:"file"`
+	// Function is the function declaration that encloses the calls, "" outside of functions.
+	Function string `json:"function,omitempty"`
+	common.InventoryEntry
+	// Count is the number of the calls.
+	Count int `json:"count"`
+}
+
+// InventoryMain runs the analysis on args, the command line without -inventory, with the
+// -inventory-calls flag of the analyzer, and writes the inventory of the driver calls to stdout in
+// format. It returns the exit code: 0 once the inventory is written, and 1 if the analysis or a
+// package failed.
+func InventoryMain(format string, args []string) int {
+	if !contains(InventoryFormats, format) {
+		fmt.Fprintf(os.Stderr, "gostable: unknown -inventory %q, want one of %s\n", format, strings.Join(InventoryFormats, ", "))
+		return 1
+	}
+
+	results, err := analyzePackages(append([]string{"-inventory-calls"}, args...))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gostable: %v\n", err)
+		return 1
+	}
+	root, err := moduleRoot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gostable: %v\n", err)
+		return 1
+	}
+	entries := inventory(results, newSources(root))
+	failures := packageFailures(results)
+	switch format {
+	case "csv":
+		err = writeInventoryCSV(os.Stdout, entries)
+	case "json":
+		err = writeInventoryJSON(os.Stdout, entries)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gostable: %v\n", err)
+		return 1
+	}
+	for _, failure := range failures {
+		fmt.Fprintf(os.Stderr, "gostable: %s\n", failure)
+	}
+	if len(failures) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// inventory returns the entries of the calls in the results of the analyzer, sorted by package, file,
+// function and method.
+func inventory(results []packageResult, src *sources) []*inventoryEntry {
+	keys := newBaselineKeys(src)
+	var entries []*inventoryEntry
+	byKey := map[string]*inventoryEntry{}
+	// A file of a package with tests is analyzed twice, in the package and in its test variant.
+	seen := map[string]bool{}
+	for _, r := range results {
+		if r.result == nil {
+			continue
+		}
+		for _, call := range r.result.Calls {
+			position := r.pkg.Fset.Position(call.Pos)
+			details := fmt.Sprint(call.InventoryEntry)
+			if seen[position.String()+"\x00"+details] {
+				continue
+			}
+			seen[position.String()+"\x00"+details] = true
+
+			loc := Location{File: position.Filename, Line: position.Line, Column: position.Column}
+			file, _ := src.rel(loc.File)
+			entry := &inventoryEntry{Package: r.pkg.PkgPath, File: file, Function: keys.function(loc), InventoryEntry: call.InventoryEntry}
+			key := strings.Join([]string{entry.Package, entry.File, entry.Function, details}, "\x00")
+			if e, ok := byKey[key]; ok {
+				e.Count++
+				continue
+			}
+			entry.Count = 1
+			byKey[key] = entry
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case a.Package != b.Package:
+			return a.Package < b.Package
+		case a.File != b.File:
+			return a.File < b.File
+		case a.Function != b.Function:
+			return a.Function < b.Function
+		case a.Receiver != b.Receiver:
+			return a.Receiver < b.Receiver
+		}
+		return a.Method < b.Method
+	})
+	return entries
+}
+
+// writeInventoryJSON writes the entries as a JSON array.
+func writeInventoryJSON(w io.Writer, entries []*inventoryEntry) error {
+	if entries == nil {
+		entries = []*inventoryEntry{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// inventoryColumns are the columns of the csv format. The lists are separated by semicolons.
+var inventoryColumns = []string{"package", "file", "function", "driver", "receiver", "method", "via", "commands",
+	"database", "collection", "options", "options_resolved", "stability", "count"}
+
+// writeInventoryCSV writes the entries as CSV, with a header.
+func writeInventoryCSV(w io.Writer, entries []*inventoryEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(inventoryColumns); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{e.Package, e.File, e.Function, e.Driver, e.Receiver, e.Method, e.Via, strings.Join(e.Commands, ";"),
+			e.Database, e.Collection, strings.Join(e.Options, ";"), strconv.FormatBool(e.OptionsResolved), e.Stability, strconv.Itoa(e.Count)}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,79 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonFinding is a finding of the json format.
+type jsonFinding struct {
+	Rule     string `json:"rule"`
+	Category string `json:"category"`
+	// Severity is unknown for the findings that have none, such as those on the clients.
+	Severity string `json:"severity"`
+	// File is relative to the root of the module, in slash form, when it is inside.
+	File        string         `json:"file"`
+	Line        int            `json:"line"`
+	Col         int            `json:"col"`
+	Symbol      string         `json:"symbol,omitempty"`
+	Entry       *jsonEntry     `json:"catalogEntry,omitempty"`
+	Remediation string         `json:"remediation,omitempty"`
+	Message     string         `json:"message"`
+	Related     []jsonLocation `json:"related,omitempty"`
+}
+
+// jsonEntry is the catalog entry that a finding matched.
+type jsonEntry struct {
+	Section string   `json:"section"`
+	Package string   `json:"package,omitempty"`
+	Type    string   `json:"type,omitempty"`
+	Name    string   `json:"name"`
+	Field   string   `json:"field,omitempty"`
+	Since   []string `json:"since,omitempty"`
+}
+
+type jsonLocation struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Col     int    `json:"col"`
+	Message string `json:"message"`
+}
+
+// writeJSON writes findings as a JSON array of objects, with the details of each finding and its
+// locations relative to root. Columns are in bytes, as in the text format.
+func writeJSON(w io.Writer, findings []Finding, root string) error {
+	src := newSources(root)
+
+	out := []jsonFinding{}
+	for _, finding := range findings {
+		details := finding.Details
+		severity := finding.Severity
+		if severity == "" {
+			severity = "unknown"
+		}
+		rel, _ := src.rel(finding.Location.File)
+		jf := jsonFinding{
+			Rule:        finding.Rule,
+			Category:    details.Category,
+			Severity:    severity,
+			File:        rel,
+			Line:        finding.Location.Line,
+			Col:         finding.Location.Column,
+			Symbol:      details.Symbol,
+			Remediation: details.Remediation,
+			Message:     finding.Message,
+		}
+		if e := details.Entry; e != nil {
+			jf.Entry = &jsonEntry{Section: e.Section, Package: e.Package, Type: e.Type, Name: e.Name, Field: e.Field, Since: e.Since}
+		}
+		for _, related := range finding.Related {
+			rel, _ := src.rel(related.File)
+			jf.Related = append(jf.Related, jsonLocation{File: rel, Line: related.Line, Col: related.Column, Message: related.Message})
+		}
+		out = append(out, jf)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,240 @@
+// Package report writes the findings of gostable in the formats of the -format flag, other than text,
+// and compares them with a baseline.
+//
+// The text format is printed by singlechecker, which prints the diagnostics only, so the other formats,
+// the baseline and the inventory run the analyzer in process and read its results.
+package report
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gostable/common"
+)
+
+// Formats are the values of the -format flag; text is what singlechecker prints.
+var Formats = []string{"text", "json", "sarif"}
+
+// FormatFlag returns the value of the -format flag in args, "" if it is not set, and args without it.
+// It is read before singlechecker parses the flags, which it does not know.
+func FormatFlag(args []string) (string, []string) {
+	return cutFlag(args, "format")
+}
+
+// BaselineFlag returns the value of the -baseline flag in args, "" if it is not set, and args without it.
+func BaselineFlag(args []string) (string, []string) {
+	return cutFlag(args, "baseline")
+}
+
+// cutFlag returns the value of the flag name in args, "" if it is not set, and args without it.
+func cutFlag(args []string, flagName string) (string, []string) {
+	value := ""
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, v, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != flagName {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			v = args[i]
+		}
+		value = v
+	}
+	return value, rest
+}
+
+// Finding is a diagnostic of gostable.
+type Finding struct {
+	// Rule is the ID of the rule, e.g. GS001-unstable-method.
+	Rule    string
+	Message string
+	// Package is the path of the package that the finding was reported in.
+	Package string
+	// Severity is error, warning or unknown for unsupported usage, after the client that runs it,
+	// and "" for the findings on the clients themselves.
+	Severity string
+	Location Location
+	Related  []Location
+	// Details are what the json format tells of the finding besides its message.
+	Details common.FindingDetails
+}
+
+// Location is a position in a file, with a message for related locations.
+type Location struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+// Main runs the analysis on args, the command line without -format and -baseline, and writes its
+// findings in format: to stdout, or as singlechecker does to stderr for text. With a baseline file,
+// only the findings that are not in the baseline are written, and the entries of the baseline that
+// were fixed are listed on stderr. It returns the exit code: 1 if the analysis or a package failed,
+// 3 if there are findings in the text format, as singlechecker does, and 0 otherwise, as the other
+// formats are read by tools.
+func Main(format, baselineFile string, args []string) int {
+	if !contains(Formats, format) {
+		fmt.Fprintf(os.Stderr, "gostable: unknown -format %q, want one of %s\n", format, strings.Join(Formats, ", "))
+		return 1
+	}
+	var base *baseline
+	if baselineFile != "" {
+		var err error
+		if base, err = readBaseline(baselineFile); err != nil {
+			fmt.Fprintf(os.Stderr, "gostable: %v\n", err)
+			return 1
+		}
+	}
+
+	findings, failures, err := analyze(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gostable: %v\n", err)
+		return 1
+	}
+	// the rules are described from the catalog of the flags, which the analysis has parsed
+	rules, err := common.Rules()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gostable: %v\n", err)
+		return 1
+	}
+	root, err := moduleRoot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gostable: %v\n", err)
+		return 1
+	}
+	var fixed []baselineEntry
+	if base != nil {
+		findings, fixed = base.compare(findings, failures, newSources(root))
+	}
+
+	switch format {
+	case "text":
+		writeText(os.Stderr, findings)
+	case "json":
+		err = writeJSON(os.Stdout, findings, root)
+	case "sarif":
+		err = writeSARIF(os.Stdout, rules, findings, failures, root, base != nil)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gostable: %v\n", err)
+		return 1
+	}
+	for _, entry := range fixed {
+		fmt.Fprintf(os.Stderr, "gostable: fixed since the baseline: %s\n", entry)
+	}
+	for _, failure := range failures {
+		fmt.Fprintf(os.Stderr, "gostable: %s\n", failure)
+	}
+	switch {
+	case len(failures) > 0:
+		return 1
+	case format == "text" && len(findings) > 0:
+		return 3
+	}
+	return 0
+}
+
+// writeText writes findings as singlechecker does, one per line.
+func writeText(w io.Writer, findings []Finding) {
+	for _, finding := range findings {
+		loc := finding.Location
+		fmt.Fprintf(w, "%s:%d:%d: %s\n", loc.File, loc.Line, loc.Column, finding.Message)
+	}
+}
+
+// analyze runs the analyzer on args, the flags of the analyzer and the package patterns, and returns
+// its findings, sorted by position, and the errors of the packages that could not be analyzed.
+func analyze(args []string) ([]Finding, []string, error) {
+	results, err := analyzePackages(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	var findings []Finding
+	for _, r := range results {
+		if r.result == nil {
+			continue
+		}
+		for _, f := range r.result.Findings {
+			finding := Finding{Rule: f.Category, Message: f.Message, Package: r.pkg.PkgPath, Severity: f.Severity,
+				Location: location(r.pkg.Fset, f.Pos, ""), Details: f.Details}
+			for _, related := range f.Related {
+				finding.Related = append(finding.Related, location(r.pkg.Fset, related.Pos, related.Message))
+			}
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i].Location, findings[j].Location
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return findings[i].Message < findings[j].Message
+	})
+	// A file of a package with tests is analyzed twice, in the package and in its test variant.
+	unique := findings[:0]
+	for _, finding := range findings {
+		if n := len(unique); n > 0 && unique[n-1].Location == finding.Location && unique[n-1].Message == finding.Message {
+			continue
+		}
+		unique = append(unique, finding)
+	}
+	return unique, packageFailures(results), nil
+}
+
+// location returns the location of pos, with a message for related locations.
+func location(fset *token.FileSet, pos token.Pos, message string) Location {
+	position := fset.Position(pos)
+	return Location{File: position.Filename, Line: position.Line, Column: position.Column, Message: message}
+}
+
+// packagePath returns the path of the package of a package ID of singlechecker, which names a test
+// variant after the package, e.g. "example.com/app [example.com/app.test]".
+func packagePath(id string) string {
+	path, _, _ := strings.Cut(id, " ")
+	return path
+}
+
+// moduleRoot returns the directory of the go.mod file of the current directory.
+func moduleRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return d, nil
+		}
+		if filepath.Dir(d) == d {
+			return dir, nil
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gostable/common"
+)
+
+// The SARIF 2.1.0 log of one run of gostable, with the properties that it fills in.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	Invocations        []sarifInvocation           `json:"invocations"`
+	OriginalURIBaseIDs map[string]sarifArtifactLoc `json:"originalUriBaseIds"`
+	ColumnKind         string                      `json:"columnKind"`
+	Results            []sarifResult               `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	FullDescription      *sarifMessage      `json:"fullDescription,omitempty"`
+	HelpURI              string             `json:"helpUri"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	RelatedLocations    []sarifLocation   `json:"relatedLocations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	BaselineState       string            `json:"baselineState,omitempty"`
+	Properties          map[string]string `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               *int                  `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLoc `json:"artifactLocation"`
+	Region           *sarifRegion     `json:"region,omitempty"`
+}
+
+type sarifArtifactLoc struct {
+	URI         string        `json:"uri,omitempty"`
+	URIBaseID   string        `json:"uriBaseId,omitempty"`
+	Description *sarifMessage `json:"description,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// srcRoot is the base of the locations, the root of the module, which is left for the consumer to
+// resolve so that the log is the same wherever the module is checked out.
+const srcRoot = "SRCROOT"
+
+// fingerprintKey is the key of the partial fingerprints of the results. The version changes if the
+// way they are computed does.
+const fingerprintKey = "gostable/v1"
+
+// writeSARIF writes findings as a SARIF log with a single run, whose rule descriptors are rules and
+// whose locations are relative to root. The packages that failed are tool execution notifications.
+// The results are marked new when findings are those that are not in a baseline.
+func writeSARIF(w io.Writer, rules []common.RuleDescriptor, findings []Finding, failures []string, root string, baselined bool) error {
+	src := newSources(root)
+
+	driver := sarifDriver{Name: "gostable", InformationURI: "https://github.com/fsnow/gostable"}
+	ruleIndex := map[string]int{}
+	for i, rule := range rules {
+		ruleIndex[rule.ID] = i
+		sr := sarifRule{
+			ID:                   rule.ID,
+			Name:                 rule.Name,
+			ShortDescription:     sarifMessage{rule.ShortDescription},
+			HelpURI:              rule.HelpURI,
+			DefaultConfiguration: sarifConfiguration{Level: rule.DefaultLevel},
+		}
+		if rule.FullDescription != "" {
+			sr.FullDescription = &sarifMessage{rule.FullDescription}
+		}
+		driver.Rules = append(driver.Rules, sr)
+	}
+
+	invocation := sarifInvocation{ExecutionSuccessful: len(failures) == 0}
+	for _, failure := range failures {
+		invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications,
+			sarifNotification{Level: "error", Message: sarifMessage{failure}})
+	}
+
+	run := sarifRun{
+		Tool:        sarifTool{Driver: driver},
+		Invocations: []sarifInvocation{invocation},
+		OriginalURIBaseIDs: map[string]sarifArtifactLoc{
+			srcRoot: {Description: &sarifMessage{"The root of the Go module that gostable was run in"}},
+		},
+		ColumnKind: "unicodeCodePoints",
+		Results:    []sarifResult{},
+	}
+
+	// occurrences counts the findings with the same fingerprint input, which tells them apart
+	occurrences := map[string]int{}
+	for _, finding := range findings {
+		index, ok := ruleIndex[finding.Rule]
+		if !ok {
+			continue
+		}
+		result := sarifResult{
+			RuleID:    finding.Rule,
+			RuleIndex: index,
+			Level:     sarifLevel(finding.Severity, rules[index].DefaultLevel),
+			Message:   sarifMessage{finding.Message},
+			Locations: []sarifLocation{{PhysicalLocation: src.location(finding.Location)}},
+		}
+		for i, related := range finding.Related {
+			id := i + 1
+			result.RelatedLocations = append(result.RelatedLocations, sarifLocation{
+				ID:               &id,
+				PhysicalLocation: src.location(related),
+				Message:          &sarifMessage{related.Message},
+			})
+		}
+		if baselined {
+			result.BaselineState = "new"
+		}
+		if finding.Severity != "" {
+			result.Properties = map[string]string{"severity": finding.Severity}
+		}
+
+		file, _ := src.rel(finding.Location.File)
+		input := strings.Join([]string{finding.Rule, file, src.lineText(finding.Location)}, "\x00")
+		occurrences[input]++
+		sum := sha256.Sum256([]byte(input + "\x00" + strconv.Itoa(occurrences[input])))
+		result.PartialFingerprints = map[string]string{fingerprintKey: hex.EncodeToString(sum[:16])}
+
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifLevel returns the level of a result of severity, or the default level of its rule.
+func sarifLevel(severity, defaultLevel string) string {
+	switch severity {
+	case "error", "warning":
+		return severity
+	case "unknown":
+		return "note"
+	}
+	return defaultLevel
+}
+
+// sources reads the lines of the source files, for the columns in code points and the fingerprints.
+type sources struct {
+	root  string
+	lines map[string][]string
+}
+
+func newSources(root string) *sources {
+	return &sources{root: root, lines: map[string][]string{}}
+}
+
+// rel returns file relative to the root in slash form, or file itself and false if it is outside the root.
+func (s *sources) rel(file string) (string, bool) {
+	rel, err := filepath.Rel(s.root, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(file), false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// location returns the physical location of loc, relative to the root when it is inside.
+func (s *sources) location(loc Location) sarifPhysicalLocation {
+	artifact := sarifArtifactLoc{URI: "file://" + filepath.ToSlash(loc.File)}
+	if rel, ok := s.rel(loc.File); ok {
+		artifact = sarifArtifactLoc{URI: rel, URIBaseID: srcRoot}
+	}
+	physical := sarifPhysicalLocation{ArtifactLocation: artifact}
+	if loc.Line > 0 {
+		physical.Region = &sarifRegion{StartLine: loc.Line, StartColumn: s.column(loc)}
+	}
+	return physical
+}
+
+// line returns the text of the line of loc, or "" if the file cannot be read.
+func (s *sources) line(loc Location) string {
+	lines, ok := s.lines[loc.File]
+	if !ok {
+		if data, err := os.ReadFile(loc.File); err == nil {
+			lines = strings.Split(string(data), "\n")
+		}
+		s.lines[loc.File] = lines
+	}
+	if loc.Line < 1 || loc.Line > len(lines) {
+		return ""
+	}
+	return lines[loc.Line-1]
+}
+
+// lineText returns the line of loc with its spaces collapsed, which does not change when the code is
+// reindented or moved to another line.
+func (s *sources) lineText(loc Location) string {
+	return strings.Join(strings.Fields(s.line(loc)), " ")
+}
+
+// column converts the column of loc, a byte offset, to code points.
+func (s *sources) column(loc Location) int {
+	line := s.line(loc)
+	if loc.Column < 1 || loc.Column-1 > len(line) {
+		return loc.Column
+	}
+	return utf8.RuneCountInString(line[:loc.Column-1]) + 1
+}
@@ -1,11 +1,30 @@
 package main
 
 import (
+	"os"
+
 	"gostable/common"
+	"gostable/report"
 
 	"golang.org/x/tools/go/analysis/singlechecker"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "baseline" {
+		os.Exit(report.BaselineMain(os.Args[2:]))
+	}
+	format, args := report.FormatFlag(os.Args[1:])
+	baseline, args := report.BaselineFlag(args)
+	inventory, args := report.InventoryFlag(args)
+	if inventory != "" {
+		os.Exit(report.InventoryMain(inventory, args))
+	}
+	if format == "" {
+		format = "text"
+	}
+	if format != "text" || baseline != "" {
+		os.Exit(report.Main(format, baseline, args))
+	}
+	os.Args = append(os.Args[:1], args...)
 	singlechecker.Main(common.StableAnalyzer)
 }
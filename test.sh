@@ -1,76 +1,142 @@
 ./build.sh
 
-cd testdata/unstable/
-go build
-
-GOSTABLE_OUT=$(../../gostable . 2>&1)
-
-# Initialize the CLIPPED_OUT variable
-CLIPPED_OUT=""
-
-# Process the output line by line
-while IFS= read -r line; do
-    # Remove the path before "gostable/testdata"
-    modified_line="${line#*/gostable/testdata}"
-    
-    # Prepend "gostable/testdata" to the modified line
-    modified_line="gostable/testdata$modified_line"
-    
-    # Append the modified line to CLIPPED_OUT
-    CLIPPED_OUT+="$modified_line"$'\n'
-done < <(echo "$GOSTABLE_OUT")
-
-# Remove the trailing newline character from CLIPPED_OUT
-CLIPPED_OUT="${CLIPPED_OUT%$'\n'}"
-
-#echo "Modified output:"
-#echo "$CLIPPED_OUT"
-
-# Compare CLIPPED_OUT with the contents of the file
-diff -u <(echo "$CLIPPED_OUT") golden
-
-# Check the exit status of the diff command
-if [ $? -eq 0 ]; then
-    echo "gostable output matches testdata/unstable/golden"
-else
-    echo "gostable output does not match testdata/unstable/golden"
-fi
-
-cd ../stable
-go build
-
-GOSTABLE_OUT=$(../../gostable . 2>&1)
-
-# Initialize the CLIPPED_OUT variable
-CLIPPED_OUT=""
-
-# Process the output line by line
-while IFS= read -r line; do
-    # Remove the path before "gostable/testdata"
-    modified_line="${line#*/gostable/testdata}"
-    
-    # Prepend "gostable/testdata" to the modified line
-    modified_line="gostable/testdata$modified_line"
-    
-    # Append the modified line to CLIPPED_OUT
-    CLIPPED_OUT+="$modified_line"$'\n'
-done < <(echo "$GOSTABLE_OUT")
-
-# Remove the trailing newline character from CLIPPED_OUT
-CLIPPED_OUT="${CLIPPED_OUT%$'\n'}"
-
-#echo "Modified output:"
-#echo "$CLIPPED_OUT"
-
-# Compare CLIPPED_OUT with the contents of the file
-diff -u <(echo "$CLIPPED_OUT") golden
-
-# Check the exit status of the diff command
-if [ $? -eq 0 ]; then
-    echo "gostable output matches testdata/stable/golden"
-else
-    echo "gostable output does not match testdata/stable/golden"
-fi
-
-
-cd ..
+# check_golden DIR GOLDEN [GOSTABLE_ARGS...]
+# Runs gostable in testdata/DIR and compares its output with testdata/DIR/GOLDEN.
+check_golden() {
+    local dir=$1
+    local golden=$2
+    shift 2
+
+    pushd "testdata/$dir" > /dev/null
+    go build -o /dev/null
+
+    GOSTABLE_OUT=$(../../gostable "$@" . 2>&1)
+
+    # Initialize the CLIPPED_OUT variable
+    CLIPPED_OUT=""
+
+    # Process the output line by line
+    while IFS= read -r line; do
+        # Skip the empty line of an empty output
+        if [ -z "$line" ]; then
+            continue
+        fi
+
+        # Remove the path before "gostable/testdata", in the text format
+        modified_line="$line"
+        if [[ "$line" == */gostable/testdata* ]]; then
+            modified_line="gostable/testdata${line#*/gostable/testdata}"
+        fi
+
+        # Append the modified line to CLIPPED_OUT
+        CLIPPED_OUT+="$modified_line"$'\n'
+    done < <(echo "$GOSTABLE_OUT")
+
+    # Remove the trailing newline character from CLIPPED_OUT
+    CLIPPED_OUT="${CLIPPED_OUT%$'\n'}"
+
+    #echo "Modified output:"
+    #echo "$CLIPPED_OUT"
+
+    # Compare CLIPPED_OUT with the contents of the file
+    # An empty golden file means no output
+    diff -u <([ -n "$CLIPPED_OUT" ] && echo "$CLIPPED_OUT") "$golden"
+
+    # Check the exit status of the diff command
+    if [ $? -eq 0 ]; then
+        echo "gostable output matches testdata/$dir/$golden"
+    else
+        echo "gostable output does not match testdata/$dir/$golden"
+    fi
+
+    popd > /dev/null
+}
+
+# check_fix DIR [GOSTABLE_ARGS...]
+# Applies the suggested fixes of gostable to a copy of testdata/DIR, and compares each fixed file with
+# its .golden file in testdata/DIR. The fixed copy must build.
+check_fix() {
+    local dir=$1
+    shift
+
+    local gostable="$PWD/gostable"
+    local copy
+    copy=$(mktemp -d)
+    cp -r "testdata/$dir/." "$copy"
+    pushd "$copy" > /dev/null
+
+    "$gostable" -fix "$@" . > /dev/null 2>&1
+
+    local status=0
+    for golden in *.go.golden; do
+        diff -u "$golden" "${golden%.golden}" || status=1
+    done
+    go build -o /dev/null || status=1
+
+    if [ $status -eq 0 ]; then
+        echo "gostable -fix output matches testdata/$dir/*.go.golden"
+    else
+        echo "gostable -fix output does not match testdata/$dir/*.go.golden"
+    fi
+
+    popd > /dev/null
+    rm -rf "$copy"
+}
+
+# check_baseline DIR [GOSTABLE_ARGS...]
+# Writes the baseline of a copy of testdata/DIR and compares it with testdata/DIR/baseline.json. Then
+# moves every line of the copy down and checks that the baseline still holds all of the findings.
+check_baseline() {
+    local dir=$1
+    shift
+
+    local gostable="$PWD/gostable"
+    local copy
+    copy=$(mktemp -d)
+    cp -r "testdata/$dir/." "$copy"
+    rm "$copy/baseline.json"
+    pushd "$copy" > /dev/null
+
+    local status=0
+    "$gostable" baseline write "$@" ./... > /dev/null 2>&1 || status=1
+    diff -u "$OLDPWD/testdata/$dir/baseline.json" gostable-baseline.json || status=1
+
+    for file in $(find . -name '*.go'); do
+        sed -i '1i // Moved down by check_baseline.\n' "$file"
+    done
+    "$gostable" -baseline=gostable-baseline.json "$@" ./... || status=1
+
+    if [ $status -eq 0 ]; then
+        echo "gostable baseline matches testdata/$dir/baseline.json"
+    else
+        echo "gostable baseline does not match testdata/$dir/baseline.json"
+    fi
+
+    popd > /dev/null
+    rm -rf "$copy"
+}
+
+check_golden unstable golden
+GODEBUG=gotypesalias=1 check_golden unstable golden
+check_golden unstable golden.baseline -baseline=baseline.json
+check_golden unstable golden.json -format=json
+check_golden unstable golden.filtered -severity=error -exclude=GS001-unstable-method,GS008-client-config
+check_golden unstable golden.inventory.csv -inventory=csv
+check_golden stable golden
+check_golden catalog golden -catalog=overlay.yaml
+check_golden catalog golden.replace -catalog=replace.json
+check_golden stable golden.5.0.3 -server-version=5.0.3
+check_golden stable golden.5.0.3.sarif -server-version=5.0.3 -format=sarif
+check_golden v2 golden
+check_golden v2 golden.json -format=json
+check_golden v2 golden.inventory.json -inventory=json
+check_golden facts golden
+check_golden fix golden -server-version=5.0.3
+check_fix fix -server-version=5.0.3
+check_baseline facts
+
+# The golangci-lint plugin, whose settings set the flags of the analyzer
+go test ./golangci
+
+# The suppression directives, placed without reading the source files
+go test ./common
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// $merge is only restricted by overlay.yaml
+func aggregateMerge() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$merge", Value: bson.D{{Key: "into", Value: "othercollection"}}}},
+	}
+
+	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}
+
+// Distinct runs distinct, which the default catalog does not support, but replace.json does
+func distinct() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	values, err := collection.Distinct(context.Background(), "category", bson.M{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(values)
+}
+
+// Drop is flagged by both overlay.yaml and replace.json
+func drop() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	err := collection.Drop(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// dbStats is listed as a stable command by overlay.yaml only
+func runCmdDbStats() {
+	db := client.Database("mydatabase")
+
+	dbStatsCommand := bson.D{
+		{Key: "dbStats", Value: 1},
+	}
+
+	var result bson.M
+	err := db.RunCommand(context.Background(), dbStatsCommand).Decode(&result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(result)
+}
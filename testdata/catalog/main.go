@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var client *mongo.Client
+
+func init() {
+	// Set up MongoDB client
+	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017")
+	var err error
+	client, err = mongo.Connect(context.Background(), clientOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Exercises the -catalog flag. overlay.yaml adds rules on top of the default catalog,
+// replace.json replaces the default catalog.
+func main() {
+	// Array of functions to be called
+	functions := []func(){
+		aggregateMerge,
+		distinct,
+		drop,
+		runCmdDbStats,
+	}
+
+	// Execute each function
+	for _, fn := range functions {
+		fn()
+	}
+
+	// Disconnect from MongoDB
+	err := client.Disconnect(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,38 @@
+// Package dbutil holds the options shared by the services.
+package dbutil
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultFindOptions are the options of long running queries.
+func DefaultFindOptions() *options.FindOptions {
+	opts := options.Find()
+	opts.SetNoCursorTimeout(true)
+	return opts.SetBatchSize(100)
+}
+
+// PagedFindOptions are the default options with a limit.
+func PagedFindOptions(limit int64) *options.FindOptions {
+	return DefaultFindOptions().SetLimit(limit)
+}
+
+// LatestFindOneOptions finds the latest document, with an index hint.
+func LatestFindOneOptions() *options.FindOneOptions {
+	return &options.FindOneOptions{
+		Sort:    map[string]int{"_id": -1},
+		Max:     map[string]int{"_id": 1000},
+		MaxTime: durationPtr(time.Second),
+	}
+}
+
+// StableFindOptions only use supported options.
+func StableFindOptions() *options.FindOptions {
+	return options.Find().SetLimit(10).SetSort(map[string]int{"_id": 1})
+}
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"facts/dbutil"
+	"facts/pipelines"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var client *mongo.Client
+
+func init() {
+	// Set up MongoDB client
+	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017")
+	var err error
+	client, err = mongo.Connect(context.Background(), clientOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func main() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+	ctx := context.Background()
+
+	// Options from a helper package
+	cursor, err := collection.Find(ctx, bson.D{}, dbutil.DefaultFindOptions())
+	if err != nil {
+		log.Fatal(err)
+	}
+	cursor.Close(ctx)
+
+	opts := dbutil.PagedFindOptions(10)
+	cursor, err = collection.Find(ctx, bson.D{}, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cursor.Close(ctx)
+
+	err = collection.FindOne(ctx, bson.D{}, dbutil.LatestFindOneOptions()).Err()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cursor, err = collection.Find(ctx, bson.D{}, dbutil.StableFindOptions().SetSkip(10))
+	if err != nil {
+		log.Fatal(err)
+	}
+	cursor.Close(ctx)
+
+	// Pipelines from a helper package
+	cursor, err = collection.Aggregate(ctx, pipelines.ActiveOps())
+	if err != nil {
+		log.Fatal(err)
+	}
+	cursor.Close(ctx)
+
+	cursor, err = collection.Aggregate(ctx, pipelines.IndexStats())
+	if err != nil {
+		log.Fatal(err)
+	}
+	cursor.Close(ctx)
+
+	// Commands from a helper package
+	var result bson.M
+	err = client.Database("mydatabase").RunCommand(ctx, pipelines.Distinct("mycollection", "category")).Decode(&result)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = client.Database("mydatabase").RunCommand(ctx, pipelines.Ping()).Decode(&result)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,38 @@
+// Package pipelines holds the aggregation pipelines and commands shared by the services.
+package pipelines
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const currentOp = "$currentOp"
+
+// Ops lists the operations in progress.
+func Ops() mongo.Pipeline {
+	return mongo.Pipeline{
+		{{currentOp, bson.D{{"allUsers", true}}}},
+		{{"$match", bson.D{{"active", true}}}},
+	}
+}
+
+// IndexStats is a stage that reports the use of the indexes.
+func IndexStats() bson.D {
+	stage := bson.D{{"$indexStats", bson.D{}}}
+	return stage
+}
+
+// ActiveOps filters the operations in progress.
+func ActiveOps() mongo.Pipeline {
+	return Ops()
+}
+
+// Distinct is the command listing the distinct values of a field.
+func Distinct(coll, field string) bson.D {
+	return bson.D{{"distinct", coll}, {"key", field}}
+}
+
+// Ping is a supported command.
+func Ping() bson.D {
+	return bson.D{{"ping", 1}}
+}
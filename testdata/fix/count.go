@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The count command is not part of the Stable API before 5.0.9 and 6.0: a count written at the call is
+// rewritten with CountDocuments, whose count is returned as the n field of the result
+func countUsers(db *mongo.Database) {
+	var result bson.M
+	err := db.RunCommand(context.Background(), bson.D{{Key: "count", Value: "users"}, {Key: "query", Value: bson.D{{Key: "active", Value: true}}}}).Decode(&result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(result["n"])
+
+	if err := db.RunCommand(context.Background(), bson.D{{"count", "orders"}}).Err(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Not rewritten: the command document is not written at the call, and limit has no counterpart here
+func countNotFixed(db *mongo.Database) {
+	cmd := bson.D{{Key: "count", Value: "users"}}
+	if err := db.RunCommand(context.Background(), cmd).Err(); err != nil {
+		log.Fatal(err)
+	}
+	if err := db.RunCommand(context.Background(), bson.D{{Key: "count", Value: "users"}, {Key: "limit", Value: 10}}).Err(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Distinct on a constant field is rewritten as an aggregation
+func distinctCategories(coll *mongo.Collection) {
+	categories, err := coll.Distinct(context.Background(), "category", bson.D{{Key: "inStock", Value: true}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(categories)
+}
+
+// The path of a field in a variable is computed at run time
+func distinctField(ctx context.Context, coll *mongo.Collection, field string) []interface{} {
+	values, err := coll.Distinct(ctx, field, bson.M{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	return values
+}
+
+// Not rewritten: the options would be lost, and the context would be made twice
+func distinctNotFixed(coll *mongo.Collection) {
+	if _, err := coll.Distinct(context.Background(), "category", bson.D{}, options.Distinct().SetComment("all")); err != nil {
+		log.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := coll.Distinct(newContext(ctx), "category", bson.D{}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func newContext(ctx context.Context) context.Context {
+	return ctx
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The fix imports bson, which this file does not
+func distinctTags(coll *mongo.Collection, filter interface{}) int {
+	tags, err := coll.Distinct(context.TODO(), "tags", filter)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return len(tags)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The findings of this project have suggested fixes, which test.sh applies with -fix to a copy of it.
+// The fixed files are compared with the .go.golden files.
+
+var client *mongo.Client
+
+func init() {
+	serverAPI := options.ServerAPI(options.ServerAPIVersion1).SetStrict(true).SetDeprecationErrors(true)
+	var err error
+	client, err = mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://localhost:27017").SetServerAPIOptions(serverAPI))
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func main() {
+	coll := client.Database("shop").Collection("products")
+	distinctCategories(coll)
+	distinctTags(coll, bson.D{})
+	distinctField(context.Background(), coll, "brand")
+	countUsers(client.Database("shop"))
+	findOptions()
+	indexOptions()
+	unusedDirectives()
+}
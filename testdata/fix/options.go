@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var background = true
+
+// Setters of options that the server ignores, or that are not needed, are deleted
+func findOptions() {
+	coll := client.Database("shop").Collection("products")
+
+	opts := options.Find().SetNoCursorTimeout(true).SetLimit(10)
+	opts.SetOplogReplay(true)
+	opts.SetShowRecordID(true)
+	if _, err := coll.Find(context.Background(), bson.D{}, opts); err != nil {
+		log.Fatal(err)
+	}
+
+	// Not deleted: showRecordID would not be used any more
+	showRecordID := true
+	opts.SetShowRecordID(showRecordID)
+	if _, err := coll.Find(context.Background(), bson.D{}, opts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func indexOptions() {
+	coll := client.Database("shop").Collection("products")
+
+	name := "by_name"
+	index := mongo.IndexModel{
+		Keys: bson.D{{Key: "name", Value: 1}},
+		Options: &options.IndexOptions{
+			Name:       &name,
+			Background: &background,
+		},
+	}
+	other := mongo.IndexModel{Keys: bson.D{{Key: "price", Value: 1}}, Options: &options.IndexOptions{Background: &background}}
+	byDate := mongo.IndexModel{Keys: bson.D{{Key: "date", Value: 1}}, Options: options.Index().SetBackground(true).SetName("by_date")}
+	if _, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{index, other, byDate}); err != nil {
+		log.Fatal(err)
+	}
+}
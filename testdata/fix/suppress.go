@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Directives that suppress no finding are deleted
+func unusedDirectives() {
+	coll := client.Database("shop").Collection("products")
+
+	//gostable:ignore GS003-unstable-stage -- the pipeline used to list the running operations
+	if _, err := coll.Find(context.Background(), bson.D{}); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := coll.CountDocuments(context.Background(), bson.D{}); err != nil { //gostable:ignore GS004-unstable-command -- count was run with RunCommand
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Strings that mention restricted stages, but are not pipeline stages
+func aggregateStrings() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	fmt.Println("running $search and $currentOp is not allowed with the Stable API")
+
+	pipeline := mongo.Pipeline{
+		{{"$match", bson.D{{"title", "$search"}}}},
+		{{"$project", bson.D{{"note", "$currentOp"}}}},
+	}
+
+	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}
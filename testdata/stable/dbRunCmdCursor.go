@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func runCmdCursor() {
+	db := client.Database("mydatabase")
+
+	// listCollections returns a cursor
+	cursor, err := db.RunCommandCursor(context.Background(), bson.D{{Key: "listCollections", Value: 1}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+
+	// find, issued by hand with run command options
+	findCommand := bson.D{
+		{Key: "find", Value: "mycollection"},
+		{Key: "filter", Value: bson.D{{Key: "status", Value: "A"}}},
+		{Key: "limit", Value: 10},
+	}
+	var result bson.M
+	opts := options.RunCmd().SetReadPreference(readpref.Primary())
+	if err := db.RunCommand(context.Background(), findCommand, opts).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(result)
+
+	// aggregate with stages that are supported
+	aggregateCommand := bson.D{
+		{Key: "aggregate", Value: "mycollection"},
+		{Key: "pipeline", Value: bson.A{
+			bson.D{{Key: "$match", Value: bson.D{{Key: "status", Value: "A"}}}},
+			bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$cust_id"}}}},
+		}},
+		{Key: "cursor", Value: bson.D{}},
+	}
+	aggCursor, err := db.RunCommandCursor(context.Background(), aggregateCommand, options.RunCmd().SetReadPreference(readpref.Secondary()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer aggCursor.Close(context.Background())
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Supported commands in each of the document representations the driver accepts
+func runCmdForms() {
+	db := client.Database("mydatabase")
+	ctx := context.Background()
+	var result bson.M
+
+	if err := db.RunCommand(ctx, primitive.D{{"ping", 1}}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+	if err := db.RunCommand(ctx, bson.M{"listCollections": 1}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+	if err := db.RunCommand(ctx, map[string]interface{}{"hello": 1}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+
+	raw, err := bson.Marshal(bson.D{{Key: "count", Value: "mycollection"}, {Key: "query", Value: bson.D{}}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := db.RunCommand(ctx, bson.Raw(raw)).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type countCmd struct {
+	Count string `bson:"count"`
+	Query bson.M `bson:"query,omitempty"`
+}
+
+type matchStage struct {
+	Match bson.M `bson:"$match"`
+}
+
+type groupStage struct {
+	Group bson.M `bson:"$group"`
+}
+
+func runCmdStructs() {
+	db := client.Database("mydatabase")
+	ctx := context.Background()
+
+	var result bson.M
+	if err := db.RunCommand(ctx, countCmd{Count: "mycollection"}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+
+	pipeline := []interface{}{
+		matchStage{Match: bson.M{"status": "A"}},
+		groupStage{Group: bson.M{"_id": "$cust_id"}},
+	}
+	if _, err := db.Collection("mycollection").Aggregate(ctx, pipeline); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Commands and pipelines built up step by step, with only supported commands and stages
+func docsBuilt(fields []string) {
+	db := client.Database("mydatabase")
+	ctx := context.Background()
+	var result bson.M
+
+	// only the last definition reaches RunCommand
+	cmd := bson.D{{Key: "serverStatus", Value: 1}}
+	cmd = bson.D{}
+	cmd = append(cmd, bson.E{Key: "ping", Value: 1})
+	if err := db.RunCommand(ctx, cmd).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+
+	pipeline := mongo.Pipeline{}
+	for _, field := range fields {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.D{{Key: field, Value: bson.M{"$exists": true}}}}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}})
+	if _, err := db.Collection("mycollection").Aggregate(ctx, pipeline); err != nil {
+		log.Fatal(err)
+	}
+
+	group := bson.M{}
+	group["$group"] = bson.M{"_id": "$status"}
+	var stages []interface{}
+	stages = append(stages, group)
+	if _, err := db.Collection("mycollection").Aggregate(ctx, stages); err != nil {
+		log.Fatal(err)
+	}
+}
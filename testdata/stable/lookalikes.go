@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Types of this package whose names are those of driver types and members are not the driver's
+
+type Collection struct {
+	name string
+}
+
+func (c *Collection) Distinct(ctx context.Context, field string) []string {
+	return []string{c.name + "." + field}
+}
+
+type CursorType int
+
+const Tailable CursorType = 1
+
+type FindOptions struct {
+	NoCursorTimeout bool
+	Max             int
+}
+
+type cursorOptions struct {
+	Tailable CursorType
+}
+
+func lookalikes() {
+	coll := &Collection{name: "mycollection"}
+	fmt.Println(coll.Distinct(context.Background(), "category"))
+
+	opts := FindOptions{NoCursorTimeout: true, Max: 10}
+	opts.Max = 20
+	cursor := cursorOptions{Tailable: Tailable}
+	fmt.Println(opts, cursor.Tailable)
+}
@@ -12,7 +12,8 @@ var client *mongo.Client
 
 func init() {
 	// Set up MongoDB client
-	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017")
+	serverAPI := options.ServerAPI(options.ServerAPIVersion1).SetStrict(true).SetDeprecationErrors(true)
+	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017").SetServerAPIOptions(serverAPI)
 	var err error
 	client, err = mongo.Connect(context.Background(), clientOptions)
 	if err != nil {
@@ -27,12 +28,6 @@ Collection functions Clone(), Database(), Name()
 Database functions: Collection(), Client(), Name(), ReadConcern(), ReadPreference()
 */
 
-/*
-TODO:
-Database.RunCommand
-Database.RunCommandCursor
-*/
-
 /*
 These need "Stable" and "Unstable" test cases:
 
@@ -67,6 +62,7 @@ func main() {
 
 		// collection functions
 		aggregateStable,
+		aggregateStrings,
 		bulkWrite,
 		countDocuments,
 		deleteMany,
@@ -83,6 +79,11 @@ func main() {
 		insertOne,
 		replaceOne,
 		runCmdCount,
+		lookalikes,
+		runCmdCursor,
+		runCmdForms,
+		runCmdStructs,
+		func() { docsBuilt(nil) },
 		updateByID,
 		updateMany,
 		updateOne,
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Database level aggregation
+func aggregateDatabase() {
+	db := client.Database("admin")
+
+	pipeline := bson.A{
+		bson.D{{"$currentOp", bson.D{{"allUsers", true}}}},
+		bson.D{{"$match", bson.D{{"active", true}}}},
+	}
+
+	cursor, err := db.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}
+
+// Restricted stage in a view definition
+func createView() {
+	db := client.Database("mydatabase")
+
+	pipeline := mongo.Pipeline{
+		{{"$indexStats", bson.D{}}},
+	}
+
+	err := db.CreateView(context.Background(), "myview", "mycollection", pipeline)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Restricted stage in a $facet sub-pipeline
+func aggregateFacet() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	pipeline := mongo.Pipeline{
+		{{"$facet", bson.D{
+			{"stats", bson.A{bson.D{{"$indexStats", bson.D{}}}}},
+			{"count", bson.A{bson.D{{"$count", "n"}}}},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}
+
+// A pipeline that is not passed to the driver here
+func sessionsPipeline() mongo.Pipeline {
+	return mongo.Pipeline{
+		{{"$listSessions", bson.D{}}},
+	}
+}
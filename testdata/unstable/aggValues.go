@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Stage names from a constant and a package variable declared in another file
+func aggregateNamedStages() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	pipeline := mongo.Pipeline{
+		{{currentOpStage, bson.D{}}},
+		{{listSessionsStage, bson.D{}}},
+	}
+
+	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}
+
+// Stage name built by concatenation, and chosen on a condition
+func aggregateBuiltStages(stats bool) {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	prefix := "$"
+	stage := prefix + "planCacheStats"
+
+	countStage := "$count"
+	if stats {
+		countStage = "$indexStats"
+	}
+
+	pipeline := mongo.Pipeline{
+		{{stage, bson.D{}}},
+		{{countStage, "n"}},
+	}
+
+	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}
+
+// Stage name passed as an argument
+func aggregateStage(stage string) {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	cursor, err := collection.Aggregate(context.Background(), mongo.Pipeline{{{stage, bson.D{}}}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}
+
+func aggregateStages() {
+	aggregateStage("$match")
+	aggregateStage("$listLocalSessions")
+}
+
+// Command name passed as an argument, through a closure
+func runCmdNamed() {
+	db := client.Database("mydatabase")
+
+	run := func(name string) {
+		var result bson.M
+		err := db.RunCommand(context.Background(), bson.D{{name, "mycollection"}, {"key", "category"}}).Decode(&result)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	run(distinctCommand)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Aliases of driver types are the driver types
+
+type Coll = mongo.Collection
+
+type FindOpts = mopts.FindOptions
+
+type Doc = bson.D
+
+func aliases(coll *Coll) {
+	if _, err := coll.Distinct(context.Background(), "category", bson.D{}); err != nil {
+		log.Fatal(err)
+	}
+
+	opts := &FindOpts{}
+	opts.SetNoCursorTimeout(true)
+	tailable := mopts.Tailable
+	opts.CursorType = &tailable
+	if _, err := coll.Find(context.Background(), bson.D{}, opts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func aliasDocs(coll *Coll) {
+	var result bson.M
+	if err := coll.Database().RunCommand(context.Background(), Doc{{"distinct", "mycollection"}, {"key", "category"}}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := coll.Aggregate(context.Background(), []Doc{{{"$currentOp", Doc{}}}}); err != nil {
+		log.Fatal(err)
+	}
+}
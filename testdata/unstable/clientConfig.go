@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const uri = "mongodb://localhost:27017"
+
+// Clients must request the strict Stable API V1 with deprecation errors
+
+func clientConfig(external *options.ClientOptions) {
+	ctx := context.Background()
+
+	// not strict
+	loose := options.ServerAPI(options.ServerAPIVersion1).SetStrict(false).SetDeprecationErrors(true)
+	connect(mongo.Connect(ctx, options.Client().ApplyURI(uri).SetServerAPIOptions(loose)))
+
+	// strict mode and deprecation errors not set
+	connect(mongo.Connect(ctx, options.Client().ApplyURI(uri).SetServerAPIOptions(options.ServerAPI(options.ServerAPIVersion1))))
+
+	// deprecation errors turned off
+	strict := true
+	api := &options.ServerAPIOptions{ServerAPIVersion: options.ServerAPIVersion1, Strict: &strict}
+	api.SetDeprecationErrors(false)
+	connect(mongo.Connect(ctx, &options.ClientOptions{ServerAPIOptions: api}))
+
+	// the Stable API set in a statement, then overridden by options without it
+	opts := options.Client().ApplyURI(uri)
+	opts.SetServerAPIOptions(options.ServerAPI(options.ServerAPIVersion1).SetStrict(true).SetDeprecationErrors(true))
+	connect(mongo.Connect(ctx, opts))
+	connect(mongo.Connect(ctx, opts, options.Client().SetServerAPIOptions(options.ServerAPI(options.ServerAPIVersion1))))
+
+	// no Stable API
+	connect(mongo.Connect(ctx, &options.ClientOptions{}))
+	if c, err := mongo.NewClient(); err == nil {
+		connect(c, c.Connect(ctx))
+	}
+
+	// options of unknown origin are not reported
+	connect(mongo.Connect(ctx, external))
+}
+
+func connect(c *mongo.Client, err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := c.Disconnect(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+}
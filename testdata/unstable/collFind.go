@@ -209,3 +209,14 @@ func find7() {
 		log.Fatal(err)
 	}
 }
+
+// An options setter in parentheses, still passed to the driver
+func findParenSetter() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	cursor, err := collection.Find(context.Background(), bson.D{}, (options.Find().SetShowRecordID)(true))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Options that embed the driver options
+type pagedFindOptions struct {
+	*options.FindOptions
+	page int64
+}
+
+// Unsupported fields set and read through selectors
+func findFields() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	b := true
+	opts := options.Find()
+	opts.ShowRecordID = &b
+	opts.Max = bson.D{{"_id", 1000}}
+	(opts.ReturnKey) = &b
+
+	paged := pagedFindOptions{FindOptions: options.Find(), page: 2}
+	paged.Min = bson.D{{"_id", 10}}
+
+	if opts.NoCursorTimeout != nil && *opts.NoCursorTimeout {
+		log.Println("no cursor timeout")
+	}
+
+	cursor, err := collection.Find(context.Background(), bson.D{}, opts, paged.FindOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}
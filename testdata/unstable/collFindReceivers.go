@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type finder struct {
+	coll *mongo.Collection
+	opts *options.FindOptions
+}
+
+func getOpts() *options.FindOptions {
+	return options.Find()
+}
+
+// Setters on receivers that are not identifiers
+func findReceivers() {
+	s := finder{
+		coll: client.Database("mydatabase").Collection("mycollection"),
+		opts: options.Find(),
+	}
+
+	// struct field
+	s.opts.SetMax(bson.D{{"_id", 1000}})
+
+	// builder chain
+	chained := options.Find().SetLimit(10).SetShowRecordID(true)
+
+	// function result
+	fromFunc := getOpts().SetNoCursorTimeout(true)
+
+	// index expression
+	all := []*options.FindOptions{options.Find()}
+	all[0].SetReturnKey(true)
+
+	cursor, err := s.coll.Find(context.Background(), bson.D{}, s.opts, chained, fromFunc, all[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+
+	// method of a collection returned by a call
+	values, err := client.Database("mydatabase").Collection("mycollection").Distinct(context.Background(), "category", bson.D{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println(values)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Watcher is implemented by the driver collections, databases and clients
+type Watcher interface {
+	Watch(ctx context.Context, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error)
+}
+
+// Distincter is implemented by the driver collections
+type Distincter interface {
+	Distinct(ctx context.Context, fieldName string, filter interface{}, opts ...*options.DistinctOptions) ([]interface{}, error)
+}
+
+// fake implements Watcher in tests
+type fake struct{}
+
+func (fake) Watch(ctx context.Context, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+	return nil, nil
+}
+
+// myDist implements Distincter without the driver
+type myDist struct{}
+
+func (myDist) Distinct(ctx context.Context, fieldName string, filter interface{}, opts ...*options.DistinctOptions) ([]interface{}, error) {
+	return nil, nil
+}
+
+func useWatcher(w Watcher) {
+	stream, err := w.Watch(context.Background(), mongo.Pipeline{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println(stream)
+}
+
+func distinctAll[T Distincter](d T) {
+	values, err := d.Distinct(context.Background(), "category", bson.D{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println(values)
+}
+
+func watchThrough(w Watcher) {
+	stream, err := w.Watch(context.Background(), mongo.Pipeline{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer stream.Close(context.Background())
+}
+
+// Interface methods are checked for the driver values that reach them, not for every driver type that
+// implements the interface
+func interfaces() {
+	ctx := context.Background()
+
+	// implemented by user types only
+	useWatcher(fake{})
+	var d Distincter = myDist{}
+	values, err := d.Distinct(ctx, "category", bson.D{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println(values)
+	distinctAll(myDist{})
+
+	// a driver collection passed as an interface
+	coll := client.Database("mydatabase").Collection("mycollection")
+	watchThrough(coll)
+
+	// an interface method expression and an interface method value
+	var api Distincter = coll
+	values, err = Distincter.Distinct(api, ctx, "category", bson.D{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println(values)
+	distinct := api.Distinct
+	values, err = distinct(ctx, "category", bson.D{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println(values)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CollectionAPI is the part of *mongo.Collection that the repositories use
+type CollectionAPI interface {
+	Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error)
+	Distinct(ctx context.Context, fieldName string, filter interface{}, opts ...*options.DistinctOptions) ([]interface{}, error)
+}
+
+// Commander runs database commands
+type Commander interface {
+	RunCommand(ctx context.Context, runCommand interface{}, opts ...*options.RunCmdOptions) *mongo.SingleResult
+}
+
+// Repo embeds the driver collection
+type Repo struct {
+	*mongo.Collection
+}
+
+type watchFunc func(ctx context.Context, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error)
+
+func runWatch(watch watchFunc) {
+	stream, err := watch(context.Background(), mongo.Pipeline{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer stream.Close(context.Background())
+}
+
+// Driver methods called through method values, embedding and interfaces
+func wrappers() {
+	coll := client.Database("mydatabase").Collection("mycollection")
+	ctx := context.Background()
+
+	// method value
+	watch := coll.Watch
+	stream, err := watch(ctx, mongo.Pipeline{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	stream.Close(ctx)
+
+	// method value passed to a function
+	runWatch(coll.Watch)
+
+	// method expression
+	values, err := (*mongo.Collection).Distinct(coll, ctx, "category", bson.D{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println(values)
+
+	// promoted method
+	repo := Repo{coll}
+	values, err = repo.Distinct(ctx, "category", bson.D{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println(values)
+
+	// interface methods
+	var api CollectionAPI = coll
+	values, err = api.Distinct(ctx, "category", bson.D{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println(values)
+
+	cursor, err := api.Aggregate(ctx, mongo.Pipeline{{{"$indexStats", bson.D{}}}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	cursor.Close(ctx)
+
+	var cmd Commander = client.Database("mydatabase")
+	var result bson.M
+	err = cmd.RunCommand(ctx, bson.D{{"distinct", "mycollection"}, {"key", "category"}}).Decode(&result)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// create and createIndexes are supported with limitations, whether they are run with RunCommand or
+// by the driver methods and options that the command model maps to them
+func runCmdCreate() {
+	db := client.Database("mydatabase")
+	ctx := context.Background()
+	var result bson.M
+
+	// capped and size are fields that create may not carry
+	if err := db.RunCommand(ctx, bson.D{{"create", "events"}, {"capped", true}, {"size", 4096}}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+	if err := db.CreateCollection(ctx, "events", options.CreateCollection().SetCapped(true)); err != nil {
+		log.Fatal(err)
+	}
+	if err := db.CreateCollection(ctx, "orders"); err != nil {
+		log.Fatal(err)
+	}
+
+	// sparse is a field of the indexes of createIndexes that they may not carry
+	indexes := bson.A{
+		bson.D{{"key", bson.D{{"sku", 1}}}, {"name", "sku_1"}, {"unique", true}},
+		bson.D{{"key", bson.D{{"tag", 1}}}, {"name", "tag_1"}, {"sparse", true}},
+	}
+	if err := db.RunCommand(ctx, bson.D{{"createIndexes", "orders"}, {"indexes", indexes}}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+	orders := db.Collection("orders")
+	if _, err := orders.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{"tag", 1}}, Options: options.Index().SetSparse(true)}); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := orders.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{"sku", 1}}, Options: options.Index().SetUnique(true)}); err != nil {
+		log.Fatal(err)
+	}
+
+	// createSearchIndexes is not supported
+	if _, err := orders.SearchIndexes().CreateOne(ctx, mongo.SearchIndexModel{Definition: bson.D{{"mappings", bson.D{{"dynamic", true}}}}}); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func runCmdCursor() {
+	db := client.Database("mydatabase")
+
+	// aggregate with a stage that is not supported
+	aggregateCommand := bson.D{
+		{Key: "aggregate", Value: 1},
+		{Key: "pipeline", Value: mongo.Pipeline{
+			{{Key: "$currentOp", Value: bson.D{}}},
+		}},
+		{Key: "cursor", Value: bson.D{}},
+	}
+	cursor, err := client.Database("admin").RunCommandCursor(context.Background(), aggregateCommand)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+
+	// a tailable find, issued by hand
+	findCommand := bson.D{
+		{Key: "find", Value: "oplog.rs"},
+		{Key: "tailable", Value: true},
+		{Key: "awaitData", Value: true},
+	}
+	opts := options.RunCmd().SetReadPreference(readpref.Primary())
+	tailCursor, err := db.RunCommandCursor(context.Background(), findCommand, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer tailCursor.Close(context.Background())
+
+	// distinct does not return a cursor, but run with options it is checked all the same
+	var result bson.M
+	err = db.RunCommand(context.Background(), bson.D{{Key: "distinct", Value: "mycollection"}, {Key: "key", Value: "category"}},
+		options.RunCmd().SetReadPreference(readpref.Nearest())).Decode(&result)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runCmdCursorUnresolved(command interface{}) {
+	cursor, err := client.Database("mydatabase").RunCommandCursor(context.Background(), command)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}
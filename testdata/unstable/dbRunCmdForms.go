@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The same distinct command in each of the document representations the driver accepts
+func runCmdForms() {
+	db := client.Database("mydatabase")
+	ctx := context.Background()
+	var result bson.M
+
+	// primitive.D with unkeyed elements
+	if err := db.RunCommand(ctx, primitive.D{{"distinct", "mycollection"}, {"key", "category"}}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+
+	// bson.E with positional fields
+	if err := db.RunCommand(ctx, bson.D{bson.E{"distinct", "mycollection"}, bson.E{"key", "category"}}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+
+	// a map with a single key
+	if err := db.RunCommand(ctx, map[string]interface{}{"validate": "mycollection"}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+	if err := db.RunCommand(ctx, bson.M{"serverStatus": 1}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+
+	// a map with several keys is encoded in any order
+	if err := db.RunCommand(ctx, bson.M{"distinct": "mycollection", "key": "category"}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+
+	// encoded to bson.Raw
+	raw, err := bson.Marshal(bson.D{{Key: "collStats", Value: "mycollection"}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := db.RunCommand(ctx, bson.Raw(raw)).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+	cmd := bson.M{"dbStats": 1}
+	data, err := bson.MarshalAppend(nil, &cmd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := db.RunCommand(ctx, data).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type distinctCmd struct {
+	Distinct string `bson:"distinct"`
+	Key      string `bson:"key"`
+}
+
+// The command name comes first through an inlined struct
+type commandHeader struct {
+	Name string `bson:"collStats"`
+}
+
+type collStatsCmd struct {
+	Header commandHeader `bson:",inline"`
+	Scale  int           `bson:"scale,omitempty"`
+}
+
+// Only the fields that are set are encoded
+type adminCmd struct {
+	Validate     string `bson:"validate,omitempty"`
+	ServerStatus int    `bson:"serverStatus,omitempty"`
+}
+
+type currentOpCmdStage struct {
+	CurrentOp bson.M `bson:"$currentOp"`
+}
+
+type searchStage struct {
+	Match  bson.M `bson:"$match,omitempty"`
+	Search bson.M `bson:"$search,omitempty"`
+}
+
+type aggregateCmd struct {
+	Aggregate interface{}   `bson:"aggregate"`
+	Pipeline  []interface{} `bson:"pipeline"`
+	Cursor    bson.M        `bson:"cursor"`
+}
+
+func runCmdStructs() {
+	db := client.Database("mydatabase")
+	ctx := context.Background()
+	var result bson.M
+
+	if err := db.RunCommand(ctx, distinctCmd{Distinct: "mycollection", Key: "category"}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+	if err := db.RunCommand(ctx, &collStatsCmd{Header: commandHeader{Name: "mycollection"}}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+	if err := db.RunCommand(ctx, adminCmd{ServerStatus: 1}).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+	runStructCommand(distinctCmd{"mycollection", "category"})
+
+	// stages as structs
+	pipeline := []interface{}{
+		searchStage{Search: bson.M{"text": bson.M{"query": "coffee", "path": "name"}}},
+		searchStage{Match: bson.M{"status": "A"}},
+	}
+	if _, err := db.Collection("mycollection").Aggregate(ctx, pipeline); err != nil {
+		log.Fatal(err)
+	}
+
+	cmd := aggregateCmd{Aggregate: 1, Pipeline: []interface{}{currentOpCmdStage{CurrentOp: bson.M{}}}, Cursor: bson.M{}}
+	cursor, err := client.Database("admin").RunCommandCursor(ctx, cmd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(ctx)
+}
+
+// The command is known from the type of the parameter
+func runStructCommand(cmd distinctCmd) {
+	var result bson.M
+	if err := client.Database("mydatabase").RunCommand(context.Background(), cmd).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The command is only known at run time
+func runCmdUnresolved(commandJSON string) {
+	db := client.Database("mydatabase")
+
+	var command bson.D
+	if err := bson.UnmarshalExtJSON([]byte(commandJSON), false, &command); err != nil {
+		log.Fatal(err)
+	}
+
+	var result bson.M
+	err := db.RunCommand(context.Background(), command).Decode(&result)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// The command comes first in base, a parameter, so the appended comment is not the command
+func runCmdAppended(base bson.D) {
+	db := client.Database("mydatabase")
+
+	var result bson.M
+	err := db.RunCommand(context.Background(), append(base, bson.E{Key: "comment", Value: "x"})).Decode(&result)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Commands and pipelines built up step by step
+func docsBuilt(verbose bool, fields []string) {
+	db := client.Database("mydatabase")
+	ctx := context.Background()
+	var result bson.M
+
+	cmd := bson.D{}
+	cmd = append(cmd, bson.E{Key: "collStats", Value: "mycollection"})
+	cmd = append(cmd, bson.E{Key: "scale", Value: 1024})
+	if err := db.RunCommand(ctx, cmd).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+
+	// the command depends on the path taken
+	var status bson.D
+	if verbose {
+		status = bson.D{{Key: "serverStatus", Value: 1}}
+	} else {
+		status = bson.D{{Key: "ping", Value: 1}}
+	}
+	if err := db.RunCommand(ctx, status).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+
+	// appended in a loop and after it
+	pipeline := mongo.Pipeline{}
+	for _, field := range fields {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.D{{Key: field, Value: bson.M{"$exists": true}}}}})
+	}
+	stats := bson.D{{Key: "$indexStats", Value: bson.M{}}}
+	pipeline = append(pipeline, stats)
+	if _, err := db.Collection("mycollection").Aggregate(ctx, pipeline); err != nil {
+		log.Fatal(err)
+	}
+
+	// stages set on maps
+	stage := bson.M{}
+	stage["$planCacheStats"] = bson.M{}
+	opStage := make(map[string]interface{})
+	opStage["$currentOp"] = bson.M{"allUsers": true}
+	if _, err := db.Collection("mycollection").Aggregate(ctx, []interface{}{stage}); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := client.Database("admin").Aggregate(ctx, bson.A{opStage}); err != nil {
+		log.Fatal(err)
+	}
+
+	// appended from another document
+	header := bson.D{{Key: "validate", Value: "mycollection"}}
+	validate := append(bson.D{}, header...)
+	validate = append(validate, bson.E{Key: "full", Value: true})
+	if err := db.RunCommand(ctx, validate).Decode(&result); err != nil {
+		log.Fatal(err)
+	}
+}
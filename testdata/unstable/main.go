@@ -25,6 +25,8 @@ func main() {
 	functions := []func(){
 		// client functions
 		watchClient,
+		func() { clientConfig(options.Client()) },
+		func() { Severities(client.Database("mydatabase").Collection("mycollection")) },
 
 		// collection functions
 		aggregateUnstable1,
@@ -33,8 +35,25 @@ func main() {
 		aggregateUnstable4,
 		aggregateUnstable5,
 		aggregateUnstable6,
+		aggregateFacet,
+		aggregateNamedStages,
+		func() { aggregateBuiltStages(true) },
+		aggregateStages,
 		runCmdDistinct1,
 		runCmdDistinct2,
+		runCmdCursor,
+		runCmdForms,
+		runCmdStructs,
+		func() { aliases(client.Database("mydatabase").Collection("mycollection")) },
+		func() { docsBuilt(true, nil) },
+		func() { suppressLine(client.Database("mydatabase").Collection("mycollection")) },
+		suppressStatement,
+		func() { suppressFunction(client.Database("mydatabase").Collection("mycollection")) },
+		func() { suppressReported(client.Database("mydatabase").Collection("mycollection")) },
+		suppressFile,
+		func() { runCmdCursorUnresolved(nil) },
+		runCmdNamed,
+		func() { runCmdUnresolved(`{"dbStats": 1}`) },
 		distinct,
 		find1,
 		find2,
@@ -43,10 +62,15 @@ func main() {
 		find5,
 		find6,
 		find7,
+		findReceivers,
+		findFields,
 		searchIndexes,
 		watchCollection,
+		wrappers,
 
 		// database functions
+		aggregateDatabase,
+		createView,
 		watchDatabase,
 	}
 
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The severity of unsupported usage follows the client that runs it
+
+var strictClient, looseClient *mongo.Client
+
+func init() {
+	var err error
+	strictAPI := options.ServerAPI(options.ServerAPIVersion1).SetStrict(true).SetDeprecationErrors(true)
+	strictClient, err = mongo.Connect(context.Background(), options.Client().ApplyURI(uri).SetServerAPIOptions(strictAPI))
+	if err != nil {
+		log.Fatal(err)
+	}
+	looseAPI := options.ServerAPI(options.ServerAPIVersion1).SetDeprecationErrors(true)
+	looseClient, err = mongo.Connect(context.Background(), options.Client().ApplyURI(uri).SetServerAPIOptions(looseAPI))
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func Severities(external *mongo.Collection) {
+	// rejected by the strict client
+	strict := strictClient.Database("mydatabase").Collection("mycollection")
+	distinctOn(strict)
+	if _, err := strict.Find(context.Background(), bson.D{}, options.Find().SetNoCursorTimeout(true)); err != nil {
+		log.Fatal(err)
+	}
+
+	// accepted by the non-strict client
+	loose := looseClient.Database("mydatabase")
+	distinctOn(loose.Collection("mycollection"))
+	if _, err := loose.Collection("mycollection").Watch(context.Background(), mongo.Pipeline{}); err != nil {
+		log.Fatal(err)
+	}
+
+	// a collection of another package may come from any client
+	if _, err := external.Distinct(context.Background(), "category", bson.D{}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// distinctOn is called with the collections of both clients
+func distinctOn(coll *mongo.Collection) {
+	if _, err := coll.Distinct(context.Background(), "category", bson.D{}); err != nil {
+		log.Fatal(err)
+	}
+}
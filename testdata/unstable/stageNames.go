@@ -0,0 +1,9 @@
+package main
+
+// Stage and command names used from other files
+const (
+	currentOpStage  = "$currentOp"
+	distinctCommand = "distinct"
+)
+
+var listSessionsStage = "$listSessions"
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Findings that are acknowledged with //gostable:ignore directives
+
+// The directive after the code suppresses the findings of its line
+func suppressLine(coll *mongo.Collection) {
+	if _, err := coll.Distinct(context.Background(), "category", bson.D{}); err != nil { //gostable:ignore GS001-unstable-method -- reporting job, run by a non-strict client
+		log.Fatal(err)
+	}
+	opts := options.Find().SetShowRecordID(true) // not suppressed
+	if _, err := coll.Find(context.Background(), bson.D{}, opts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// The directive on a line of its own suppresses the findings of the statement that follows
+func suppressStatement() {
+	db := client.Database("admin")
+
+	//gostable:ignore GS003-unstable-stage -- the ops dashboard lists the running operations
+	pipeline := mongo.Pipeline{
+		{{"$currentOp", bson.D{{"allUsers", true}}}},
+		{{"$match", bson.D{{"active", true}}}},
+	}
+	cursor, err := db.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}
+
+// suppressFunction checks the indexes in use.
+//
+//gostable:ignore GS003-unstable-stage, GS001-unstable-method -- ops tool, run by a non-strict client
+func suppressFunction(coll *mongo.Collection) {
+	pipeline := mongo.Pipeline{{{"$indexStats", bson.D{}}}}
+	if _, err := coll.Aggregate(context.Background(), pipeline); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := coll.Distinct(context.Background(), "name", bson.D{}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Directives that are reported
+func suppressReported(coll *mongo.Collection) {
+	//gostable:ignore GS001-unstable-method
+	if _, err := coll.Distinct(context.Background(), "category", bson.D{}); err != nil {
+		log.Fatal(err)
+	}
+
+	//gostable:ignore GS042-unstable-thing -- no such rule
+	if _, err := coll.Distinct(context.Background(), "category", bson.D{}); err != nil {
+		log.Fatal(err)
+	}
+
+	//gostable:ignore GS003-unstable-stage -- there is no stage here
+	if _, err := coll.Find(context.Background(), bson.D{}); err != nil {
+		log.Fatal(err)
+	}
+
+	//gostable:ignore GS001-unstable-method, GS002-unstable-field -- the field is not set here
+	if _, err := coll.Distinct(context.Background(), "category", bson.D{}); err != nil {
+		log.Fatal(err)
+	}
+}
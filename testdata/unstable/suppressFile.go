@@ -0,0 +1,21 @@
+//gostable:file-ignore GS004-unstable-command -- the commands of this file are run by the admin tool, which does not request the strict Stable API
+
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The directive at the top of the file suppresses the findings of its rule in the whole file
+func suppressFile() {
+	db := client.Database("mydatabase")
+	if err := db.RunCommand(context.Background(), bson.D{{"serverStatus", 1}}).Err(); err != nil {
+		log.Fatal(err)
+	}
+	if err := db.RunCommand(context.Background(), bson.D{{"dbStats", 1}}).Err(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func distinct() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	// Retrieve the distinct values
+	res := collection.Distinct(context.Background(), "category", bson.M{})
+	var values []string
+	if err := res.Decode(&values); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(values)
+}
+
+func watchCollection() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	changeStream, err := collection.Watch(context.Background(), mongo.Pipeline{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer changeStream.Close(context.Background())
+}
+
+func runCmdDistinct() {
+	db := client.Database("mydatabase")
+
+	// Create the "distinct" command
+	distinctCommand := bson.D{
+		{Key: "distinct", Value: "mycollection"},
+		{Key: "key", Value: "category"},
+	}
+
+	var result bson.M
+	err := db.RunCommand(context.Background(), distinctCommand).Decode(&result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(result)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Setters of the options builder
+func find1() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	findOptions := options.Find()
+	findOptions.SetShowRecordID(true)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetSort(bson.D{{"name", 1}})
+
+	cursor, err := collection.Find(context.Background(), bson.M{}, findOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}
+
+// A custom options.Lister that sets the struct fields
+type maxLister struct{}
+
+func (maxLister) List() []func(*options.FindOneOptions) error {
+	return []func(*options.FindOneOptions) error{
+		func(opts *options.FindOneOptions) error {
+			t := true
+			*opts = options.FindOneOptions{
+				Max:       bson.D{{"field", 100}},
+				ReturnKey: &t,
+			}
+			return nil
+		},
+	}
+}
+
+func find2() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	var result bson.M
+	err := collection.FindOne(context.Background(), bson.M{}, maxLister{}).Decode(&result)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Tailable cursor
+func find3() {
+	collection := client.Database("mydatabase").Collection("mycollection")
+
+	findOptions := options.Find()
+	findOptions.SetCursorType(options.TailableAwait)
+
+	cursor, err := collection.Find(context.Background(), bson.M{}, findOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}
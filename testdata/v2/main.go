@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var client *mongo.Client
+
+func init() {
+	// Set up MongoDB client
+	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017")
+	var err error
+	client, err = mongo.Connect(clientOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Uses the v2 driver, and the v1 driver next to it in mixed.go
+func main() {
+	// Array of functions to be called
+	functions := []func(){
+		// collection functions
+		distinct,
+		find1,
+		find2,
+		find3,
+		runCmdDistinct,
+		watchCollection,
+
+		// v1 driver functions
+		distinctV1,
+		findV1,
+	}
+
+	// Execute each function
+	for _, fn := range functions {
+		fn()
+	}
+}
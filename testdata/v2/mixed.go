@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	bsonv1 "go.mongodb.org/mongo-driver/bson"
+	mongov1 "go.mongodb.org/mongo-driver/mongo"
+	optionsv1 "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var clientV1 *mongov1.Client
+
+func distinctV1() {
+	collection := clientV1.Database("mydatabase").Collection("mycollection")
+
+	values, err := collection.Distinct(context.Background(), "category", bsonv1.M{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(values)
+}
+
+func findV1() {
+	collection := clientV1.Database("mydatabase").Collection("mycollection")
+
+	findOptions := optionsv1.Find()
+	findOptions.SetShowRecordID(true)
+
+	cursor, err := collection.Find(context.Background(), bsonv1.M{}, findOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(context.Background())
+}